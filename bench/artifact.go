@@ -0,0 +1,89 @@
+package bench
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+const defaultArtifactDir = "/artifacts"
+
+func artifactDir() string {
+	if dir := os.Getenv("ARTIFACT_DIR"); dir != "" {
+		return dir
+	}
+	return defaultArtifactDir
+}
+
+// artifactPath returns the path of name in artifacts, it is empty if the directory can not be created.
+func (c *cluster) artifactPath(name string) string {
+	path, err := c.artifacts.Prepare(name)
+	if err != nil {
+		log.Warn("failed to prepare artifact", zap.String("name", name), zap.Error(err))
+		return ""
+	}
+	return path
+}
+
+// writeArtifact writes data to artifacts, artifacts are best effort so that errors are only logged.
+func (c *cluster) writeArtifact(name string, data []byte) {
+	if err := c.artifacts.WriteFile(name, data); err != nil {
+		log.Warn("failed to write artifact", zap.String("name", name), zap.Error(err))
+	}
+}
+
+func (c *cluster) writeArtifactJSON(name string, v interface{}) {
+	if err := c.artifacts.WriteJSON(name, v); err != nil {
+		log.Warn("failed to write artifact", zap.String("name", name), zap.Error(err))
+	}
+}
+
+func (c *cluster) copyArtifact(name, src string) {
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		log.Warn("artifact source does not exist", zap.String("src", src))
+		return
+	}
+	if err := c.artifacts.CopyFile(name, src); err != nil {
+		log.Warn("failed to copy artifact", zap.String("name", name), zap.Error(err))
+	}
+}
+
+// renderComparison writes the comparison to artifacts in all report formats.
+func (c *cluster) renderComparison(cmp *utils.Comparison) {
+	for _, format := range c.formats {
+		r, err := utils.GetRenderer(format)
+		if err != nil {
+			log.Warn("failed to get renderer", zap.Error(err))
+			continue
+		}
+		var buf bytes.Buffer
+		if err := r.Render(&buf, cmp); err != nil {
+			log.Warn("failed to render report", zap.String("format", format), zap.Error(err))
+			continue
+		}
+		c.writeArtifact(r.FileName(), buf.Bytes())
+	}
+	if regressions := cmp.Regressions(); len(regressions) > 0 {
+		names := make([]string, 0, len(regressions))
+		for _, m := range regressions {
+			names = append(names, m.Name)
+		}
+		log.Warn("metrics regressed", zap.Strings("metrics", names))
+	}
+}
+
+// CollectArtifacts writes the metric dump and run metadata, then packs all artifacts.
+func (c *cluster) CollectArtifacts(runErr error) error {
+	if metrics, err := c.metricClient(); err == nil {
+		c.writeArtifactJSON("metrics.json", metrics.Records())
+	}
+	meta := c.runMetadata()
+	if runErr != nil && meta.Error == "" {
+		meta.Error = runErr.Error()
+	}
+	c.writeArtifactJSON("run.json", meta)
+	return c.artifacts.Pack()
+}
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/lhy1024/bench/utils"
@@ -36,10 +39,26 @@ const (
 	typeFloat64
 )
 
+const (
+	latencyQuery = "sum(tidb_server_handle_query_duration_seconds_sum{sql_type!=\"internal\"})" +
+		" / (sum(tidb_server_handle_query_duration_seconds_count{sql_type!=\"internal\"}) + 1)"
+	balanceRegionQuery = "sum(pd_scheduler_event_count{type=\"balance-region-scheduler\", name=\"schedule\"})"
+	snapshotSizeQuery  = "sum(tikv_snapshot_size_sum)"
+	// defaultBalanceTimeout is the timeout of stores to be balanced after a scale out.
+	defaultBalanceTimeout = time.Hour
+)
+
 type scaleOut struct {
-	c   *cluster
-	t   timePoint
-	num int //scale out num
+	c         *cluster
+	t         timePoint
+	num       int //scale out num
+	steps     []int
+	tolerance float64
+	// balanceTimeout is set by BALANCE_TIMEOUT in seconds
+	balanceTimeout time.Duration
+	// added is the stores which are added by the last run
+	added   []utils.StoreStats
+	results []*utils.ScaleOutOnce
 }
 
 func newScaleOut(c *cluster) bench {
@@ -47,55 +66,221 @@ func newScaleOut(c *cluster) bench {
 	if err != nil {
 		num = 1 // default
 	}
+	tolerance, err := strconv.ParseFloat(os.Getenv("BALANCE_TOLERANCE"), 64)
+	if err != nil {
+		tolerance = utils.DefaultBalanceTolerance
+	}
+	// SCALE_STEPS is the store count after each step, such as 4,6,8
+	var steps []int
+	for _, step := range strings.Split(os.Getenv("SCALE_STEPS"), ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(step)); err == nil {
+			steps = append(steps, n)
+		}
+	}
+	balanceTimeout := defaultBalanceTimeout
+	if sec, err := strconv.Atoi(os.Getenv("BALANCE_TIMEOUT")); err == nil && sec > 0 {
+		balanceTimeout = time.Duration(sec) * time.Second
+	}
 	return &scaleOut{
-		c:   c,
-		num: num,
+		c:              c,
+		num:            num,
+		steps:          steps,
+		tolerance:      tolerance,
+		balanceTimeout: balanceTimeout,
 	}
 }
 
 func (s *scaleOut) Run() error {
-	preStoreNum := s.c.getStoreNum()
-	for i := 0; i < s.num; i++ {
-		if err := s.c.AddStore(); err != nil {
-			return err
-		}
+	s.c.SetConfig("BALANCE_TOLERANCE", strconv.FormatFloat(s.tolerance, 'f', -1, 64))
+	s.c.SetConfig("BALANCE_TIMEOUT", strconv.Itoa(int(s.balanceTimeout.Seconds())))
+	if len(s.steps) > 0 {
+		return s.runSteps()
+	}
+	s.c.SetConfig("SCALE_NUM", strconv.Itoa(s.num))
+	up, err := s.c.getUpStoreNum()
+	if err != nil {
+		return err
+	}
+	deploy, register, err := s.addStores(up + s.num)
+	if err != nil {
+		return err
 	}
-	s.waitScaleOut(preStoreNum)
 	s.t.addTime = time.Now()
-	for {
-		bal, err := s.isBalance()
+	if err := s.waitBalance(); err != nil {
+		return err
+	}
+	// every run is an iteration of the report
+	rep, err := s.createOnce()
+	if err != nil {
+		return err
+	}
+	rep.DeployTime, rep.RegisterTime = deploy, register
+	s.results = append(s.results, rep)
+	return nil
+}
+
+// addStores adds stores until there are target up stores in PD, then applies the store limit.
+// It returns the seconds until the platform lists the new stores in resources, and then until they are up in PD.
+func (s *scaleOut) addStores(target int) (deploy, register float64, err error) {
+	up, err := s.c.getUpStoreNum()
+	if err != nil {
+		return 0, 0, err
+	}
+	added, err := s.c.scaleOutStores(target - up)
+	if err != nil {
+		return 0, 0, err
+	}
+	s.added = append(s.added, added.stores...)
+	log.Info("stores are added", zap.Int("stores", target), zap.Float64("deploy time", added.deploy), zap.Float64("register time", added.register))
+	return added.deploy, added.register, s.applyStoreLimit()
+}
+
+// Reset removes the stores which are added by the last run, so that every iteration scales out the same topology.
+func (s *scaleOut) Reset() error {
+	added := s.added
+	s.added = nil
+	if len(added) == 0 {
+		return nil
+	}
+	return s.c.removeStores(added, s.balanceTimeout)
+}
+
+// runSteps adds stores step by step and waits for balance after each step,
+// the report of the run covers all steps and the curve is the stats of each step.
+func (s *scaleOut) runSteps() error {
+	steps := make([]string, 0, len(s.steps))
+	for _, step := range s.steps {
+		steps = append(steps, strconv.Itoa(step))
+	}
+	s.c.SetConfig("SCALE_STEPS", strings.Join(steps, ","))
+	var start time.Time
+	var curve []utils.ScaleOutStep
+	var missing []string
+	var deploy, register float64
+	for _, target := range s.steps {
+		preStoreNum, err := s.c.getUpStoreNum()
+		if err != nil {
+			return err
+		}
+		if target <= preStoreNum {
+			return errors.Errorf("step to %d stores is not more than %d stores", target, preStoreNum)
+		}
+		stepDeploy, stepRegister, err := s.addStores(target)
 		if err != nil {
 			return err
 		}
-		if bal {
-			return nil
+		s.t.addTime = time.Now()
+		if start.IsZero() {
+			start = s.t.addTime
+		}
+		if err := s.waitBalance(); err != nil {
+			return err
+		}
+		step, stepMissing, err := s.createStep(target, target-preStoreNum)
+		if err != nil {
+			return err
 		}
-		time.Sleep(time.Second)
+		step.DeployTime, step.RegisterTime = stepDeploy, stepRegister
+		deploy += stepDeploy
+		register += stepRegister
+		log.Info("scale out step is balanced", zap.Int("stores", target), zap.Float64("balance time", step.BalanceTime))
+		curve = append(curve, *step)
+		missing = append(missing, stepMissing...)
+	}
+	s.c.renderCurve(curve)
+	s.t.addTime = start
+	rep, err := s.createOnce()
+	if err != nil {
+		return err
 	}
+	rep.Steps = curve
+	rep.DeployTime, rep.RegisterTime = deploy, register
+	rep.Missing = utils.UnionMissing(rep.Missing, missing)
+	s.results = append(s.results, rep)
+	return nil
 }
 
-func (s *scaleOut) waitScaleOut(preStoreNum int) {
-	for {
-		time.Sleep(time.Second)
-		if s.num+preStoreNum == s.c.getStoreNum() {
-			return
-		}
+// createStep creates the stats of a step, missing metrics are prefixed by the store count.
+func (s *scaleOut) createStep(stores, added int) (*utils.ScaleOutStep, []string, error) {
+	step := &utils.ScaleOutStep{
+		Stores:      stores,
+		Added:       added,
+		BalanceTime: s.t.balanceTime.Sub(s.t.addTime).Seconds(),
+	}
+	step.BalanceTimePerStore = step.BalanceTime / float64(added)
+	rep := &utils.ScaleOutOnce{}
+	var prevOperators, curOperators int
+	if err := s.queryPrevCur(rep, "BalanceRegionCount", balanceRegionQuery, &prevOperators, &curOperators, typeInt); err != nil {
+		return nil, nil, err
+	}
+	step.RegionOperators = curOperators - prevOperators
+	var prevMoved, curMoved float64
+	if err := s.queryPrevCur(rep, "DataMoved", snapshotSizeQuery, &prevMoved, &curMoved, typeFloat64); err != nil {
+		return nil, nil, err
+	}
+	step.DataMoved = curMoved - prevMoved
+	var prevLatency float64
+	if err := s.queryPrevCur(rep, "Latency", latencyQuery, &prevLatency, &step.Latency, typeFloat64); err != nil {
+		return nil, nil, err
+	}
+	missing := make([]string, 0, len(rep.Missing))
+	for _, name := range rep.Missing {
+		missing = append(missing, fmt.Sprintf("step%d.%s", stores, name))
+	}
+	return step, missing, nil
+}
+
+// applyStoreLimit sets the store limit of all stores including the new ones if it is configured.
+func (s *scaleOut) applyStoreLimit() error {
+	if s.c.storeLimit == "" {
+		return nil
+	}
+	rate, err := strconv.ParseFloat(s.c.storeLimit, 64)
+	if err != nil {
+		return errors.Annotate(err, "invalid STORE_LIMIT")
+	}
+	s.c.SetConfig("STORE_LIMIT", s.c.storeLimit)
+	return s.c.SetAllStoreLimit(rate)
+}
+
+// sweepResult implements sweeper.
+func (s *scaleOut) sweepResult() (interface{}, error) {
+	if len(s.results) == 0 {
+		return nil, errors.New("no result to report")
 	}
+	rep := utils.MeanScaleOutOnce(s.results)
+	s.results = nil
+	return rep, nil
+}
+
+// waitBalance waits until stores are balanced and no balance operator is pending, it fails after the timeout.
+func (s *scaleOut) waitBalance() error {
+	return waitUntil("stores to be balanced", s.balanceTimeout, time.Second, s.isBalance)
 }
 
+// isBalance checks stores by PD at first, then the region scores in prometheus should be stable for a while.
 func (s *scaleOut) isBalance() (bool, error) {
+	bal, err := s.c.isPDBalanced(s.tolerance)
+	if err != nil || !bal {
+		return false, err
+	}
 	r := v1.Range{
 		Start: time.Now().Add(-9 * time.Minute),
 		End:   time.Now(),
 		Step:  time.Minute,
 	}
 	matrix, err := s.c.getMatrixMetric("pd_scheduler_store_status{type=\"region_score\"}", r)
-	if err != nil {
+	if isNoData(err) {
+		// prometheus may not scrape the new stores yet
+		log.Debug("store score has no data")
+		return false, nil
+	} else if err != nil {
 		return false, err
 	}
 	// if low deviation in a series of scores applies to all stores, then it is balanced.
-	for _, scores := range matrix {
+	for store, scores := range matrix {
 		if len(scores) != 10 {
+			log.Debug("store score is not enough", zap.String("store", store), zap.Int("points", len(scores)))
 			return false, nil
 		}
 		mean := 0.0
@@ -107,6 +292,7 @@ func (s *scaleOut) isBalance() (bool, error) {
 			dev += (score - mean) * (score - mean) / 10
 		}
 		if mean*mean*0.02 < dev {
+			log.Debug("store is not balanced", zap.String("store", store), zap.Float64("mean", mean), zap.Float64("dev", dev))
 			return false, nil
 		}
 	}
@@ -116,40 +302,39 @@ func (s *scaleOut) isBalance() (bool, error) {
 }
 
 func (s *scaleOut) Collect() error {
-	// create report data
-	data, err := s.createReport()
+	rep, err := s.createReport()
 	if err != nil {
 		return err
 	}
-
-	// try get last data
-	lastReport, err := s.c.GetLastReport()
+	lastReport, err := s.c.lastReportOf(rep.Meta.Case)
 	if err != nil {
 		return err
 	}
-
-	// send data
-	var plainText string
-	if lastReport == nil { //first send
-		plainText = ""
-	} else { //second send
-		plainText, err = s.mergeReport(lastReport.Data, data)
-		log.Info("Merge report success", zap.String("merge result", plainText))
-		if err != nil {
+	last := &utils.ScaleOutOnce{}
+	if lastReport != nil {
+		if err := json.Unmarshal([]byte(lastReport.Data), last); err != nil {
 			return err
 		}
 	}
-
-	return s.c.SendReport(data, plainText)
+	return s.c.collectReportWith(lastReport, rep, rep.Meta,
+		storeReport(last.StoreRegionScore, rep.StoreRegionScore),
+		storeStatsReport(last.Stores, rep.Stores),
+		curveReport(rep.Steps),
+		missingReport(rep.Missing))
 }
 
-func (s *scaleOut) queryPrevCur(query string, prevArg, curArg interface{}, typ int) error {
+// queryPrevCur queries the value at addTime and balanceTime, missing values are recorded in rep.Missing and left as 0.
+func (s *scaleOut) queryPrevCur(rep *utils.ScaleOutOnce, name, query string, prevArg, curArg interface{}, typ int) error {
 	prevValue, err := s.c.getMetric(query, s.t.addTime)
-	if err != nil {
+	if isNoData(err) {
+		rep.Missing = append(rep.Missing, "Prev"+name)
+	} else if err != nil {
 		return err
 	}
 	curValue, err := s.c.getMetric(query, s.t.balanceTime)
-	if err != nil {
+	if isNoData(err) {
+		rep.Missing = append(rep.Missing, "Cur"+name)
+	} else if err != nil {
 		return err
 	}
 	if typ == typeInt {
@@ -164,145 +349,270 @@ func (s *scaleOut) queryPrevCur(query string, prevArg, curArg interface{}, typ i
 	return nil
 }
 
-func (s *scaleOut) createReport() (string, error) {
+// deriveMissing marks the derived metric missing if any metric which it is derived from is missing.
+func deriveMissing(missing []string, derived string, from ...string) []string {
+	for _, name := range from {
+		for _, m := range missing {
+			if m == name {
+				return utils.UnionMissing(missing, []string{derived})
+			}
+		}
+	}
+	return missing
+}
+
+// createReport aggregates all iterations, the fields are the mean and Iterations keeps the value of each iteration.
+func (s *scaleOut) createReport() (*utils.ScaleOutOnce, error) {
+	if len(s.results) == 0 {
+		return nil, errors.New("no result to report")
+	}
+	agg, err := utils.Aggregate(s.results)
+	if err != nil {
+		return nil, err
+	}
+	rep := agg.(*utils.ScaleOutOnce)
+	last := s.results[len(s.results)-1]
+	rep.StoreRegionScore = last.StoreRegionScore
+	rep.Stores = last.Stores
+	rep.Steps = last.Steps
+	rep.Meta = s.c.runMetadata()
+	return rep, nil
+}
+
+func (s *scaleOut) createOnce() (*utils.ScaleOutOnce, error) {
 	rep := &utils.ScaleOutOnce{BalanceInterval: int(s.t.balanceTime.Sub(s.t.addTime).Seconds())}
-	err := s.queryPrevCur("sum(tidb_server_handle_query_duration_seconds_sum{sql_type!=\"internal\"})"+
-		" / (sum(tidb_server_handle_query_duration_seconds_count{sql_type!=\"internal\"}) + 1)",
-		&rep.PrevLatency, &rep.CurLatency, typeFloat64)
+	err := s.queryPrevCur(rep, "Latency", latencyQuery, &rep.PrevLatency, &rep.CurLatency, typeFloat64)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	err = s.queryPrevCur("pd_scheduler_event_count{type=\"balance-leader-scheduler\", name=\"schedule\"}",
+	err = s.queryPrevCur(rep, "BalanceLeaderCount", "sum(pd_scheduler_event_count{type=\"balance-leader-scheduler\", name=\"schedule\"})",
 		&rep.PrevBalanceLeaderCount, &rep.CurBalanceLeaderCount, typeInt)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	err = s.queryPrevCur("pd_scheduler_event_count{type=\"balance-region-scheduler\", name=\"schedule\"}",
-		&rep.PrevBalanceRegionCount, &rep.CurBalanceRegionCount, typeInt)
+	err = s.queryPrevCur(rep, "BalanceRegionCount", balanceRegionQuery, &rep.PrevBalanceRegionCount, &rep.CurBalanceRegionCount, typeInt)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	err = s.queryPrevCur("sum(tikv_engine_compaction_flow_bytes)", &rep.PrevCompactionRate, &rep.CurCompactionRate, typeFloat64)
+	err = s.queryPrevCur(rep, "CompactionRate", "sum(tikv_engine_compaction_flow_bytes)", &rep.PrevCompactionRate, &rep.CurCompactionRate, typeFloat64)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	rep.BalanceLeaderOperators = rep.CurBalanceLeaderCount - rep.PrevBalanceLeaderCount
+	rep.Missing = deriveMissing(rep.Missing, "BalanceLeaderOperators", "PrevBalanceLeaderCount", "CurBalanceLeaderCount")
+	rep.BalanceRegionOperators = rep.CurBalanceRegionCount - rep.PrevBalanceRegionCount
+	rep.Missing = deriveMissing(rep.Missing, "BalanceRegionOperators", "PrevBalanceRegionCount", "CurBalanceRegionCount")
+	rep.CompactionFlow = rep.CurCompactionRate - rep.PrevCompactionRate
+	rep.Missing = deriveMissing(rep.Missing, "CompactionFlow", "PrevCompactionRate", "CurCompactionRate")
 
-	err = s.queryPrevCur("sum(tikv_raftstore_apply_log_duration_seconds_sum) / (sum(tikv_raftstore_apply_log_duration_seconds_count) + 1)",
+	err = s.queryPrevCur(rep, "ApplyLog", "sum(tikv_raftstore_apply_log_duration_seconds_sum) / (sum(tikv_raftstore_apply_log_duration_seconds_count) + 1)",
 		&rep.PrevApplyLog, &rep.CurApplyLog, typeFloat64)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	err = s.queryPrevCur("sum(tikv_raftstore_apply_perf_context_time_duration_secs_sum{type=\"db_mutex_lock_nanos\"}) / "+
+	err = s.queryPrevCur(rep, "DbMutex", "sum(tikv_raftstore_apply_perf_context_time_duration_secs_sum{type=\"db_mutex_lock_nanos\"}) / "+
 		"(sum(tikv_raftstore_apply_perf_context_time_duration_secs_count{type=\"db_mutex_lock_nanos\"}) + 1)",
 		&rep.PrevDbMutex, &rep.CurDbMutex, typeFloat64)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	bytes, err := json.Marshal(rep)
-	if err != nil {
-		log.Error("marshal error", zap.Error(err))
+	scores, err := s.c.getLabeledMetric("pd_scheduler_store_status{type=\"region_score\"}", s.t.balanceTime)
+	if isNoData(err) {
+		rep.Missing = append(rep.Missing, "StoreRegionScore")
+	} else if err != nil {
+		return nil, err
 	}
+	rep.StoreRegionScore = scores
 
-	return string(bytes), err
+	stores, err := s.c.getStoreStats()
+	if err != nil {
+		return nil, err
+	}
+	rep.Stores = stores
+	regions, err := s.c.getRegionStats()
+	if err != nil {
+		return nil, err
+	}
+	rep.RegionCount = regions.Count
+	return rep, nil
 }
 
-func reportLine(head string, last float64, cur float64) string {
-	headPart := "\t* " + head + ": "
-	curPart := fmt.Sprintf("%.8f ", cur)
-	deltaPart := fmt.Sprintf("delta: %.2f%%  \n", (cur-last)*100/(last+1))
+// reportLine reports a compared metric, an improvement is prefixed by "+" and a regression by "-",
+// so that they are colored in the diff block.
+func reportLine(d utils.MetricDiff) string {
+	prefix := " "
+	switch d.Verdict {
+	case utils.VerdictImproved:
+		prefix = "+"
+	case utils.VerdictRegressed:
+		prefix = "-"
+	}
+	headPart := prefix + "\t* " + d.Name + ": "
+	curPart := fmt.Sprintf("%.8f ", d.Cur)
+	deltaPart := "delta: " + d.FormatDelta() + "  \n"
 	return headPart + curPart + deltaPart
 }
 
-// lastReport is
-func (s *scaleOut) mergeReport(lastReport, report string) (plainText string, err error) {
-	last := &utils.ScaleOutOnce{}
-	cur := &utils.ScaleOutOnce{}
-	err = json.Unmarshal([]byte(lastReport), last)
-	if err != nil {
-		return
+// configDiffReport warns that the reports are produced by different config.
+func configDiffReport(last, cur *utils.Metadata) string {
+	diffs := cur.ConfigDiff(last)
+	if len(diffs) == 0 {
+		return ""
 	}
-	err = json.Unmarshal([]byte(report), cur)
-	if err != nil {
-		return
+	log.Warn("compared reports differ in config", zap.Strings("diffs", diffs))
+	plainText := "! warning: compared reports differ in config  \n"
+	for _, diff := range diffs {
+		plainText += "!\t* " + diff + "  \n"
 	}
-	stats := &utils.ScaleOutStats{}
-	err = stats.Init(lastReport, report)
-	if err != nil {
-		return
+	return plainText
+}
+
+// storeReport reports the region score of each store after balance.
+func storeReport(last, cur map[string]float64) string {
+	if len(cur) == 0 {
+		return ""
+	}
+	stores := make([]string, 0, len(cur))
+	for store := range cur {
+		stores = append(stores, store)
+	}
+	sort.Strings(stores)
+	plainText := "store region score:  \n"
+	for _, store := range stores {
+		if lastScore, ok := last[store]; ok {
+			plainText += reportLine(utils.NewMetricDiff("store", "store_"+store, &lastScore, cur[store], utils.NoDirection, 0, true))
+		} else {
+			plainText += fmt.Sprintf(" \t* store_%s: %.8f new store  \n", store, cur[store])
+		}
 	}
-	err = stats.RenderTo("stats.html")
-	if err != nil {
-		return
+	return plainText
+}
+
+// storeStatsReport reports the leader and region count of each store from PD after balance.
+func storeStatsReport(last, cur []utils.StoreStats) string {
+	if len(cur) == 0 {
+		return ""
+	}
+	lastStores := make(map[uint64]utils.StoreStats, len(last))
+	for _, store := range last {
+		lastStores[store.ID] = store
+	}
+	plainText := "store stats:  \n"
+	for _, store := range cur {
+		lastStore, ok := lastStores[store.ID]
+		for _, count := range []struct {
+			name      string
+			last, cur int
+		}{
+			{"leader_count", lastStore.LeaderCount, store.LeaderCount},
+			{"region_count", lastStore.RegionCount, store.RegionCount},
+		} {
+			name := fmt.Sprintf("store_%d_%s", store.ID, count.name)
+			if ok {
+				lastValue := float64(count.last)
+				plainText += reportLine(utils.NewMetricDiff("store", name, &lastValue, float64(count.cur), utils.NoDirection, 0, true))
+			} else {
+				plainText += fmt.Sprintf(" \t* %s: %d %s new store  \n", name, count.cur, store.State)
+			}
+		}
 	}
-	header, err := stats.Report()
-	if err != nil {
-		return
-	}
-	vis := createArtReport("visualization", "label", header)
-	title := "```diff  \n@@\t\t\tBenchmark diff\t\t\t@@\n"
-	splitLine := ""
-	for i := 0; i < 58; i++ {
-		splitLine += "="
-	}
-	splitLine += "\n"
-	plainText += title
-	plainText += splitLine
-	plainText += vis
-	plainText += "\n"
-	balanceTag := "balance:  \n"
-	scheduleTag := "schedule:  \n"
-	compactionTag := "compaction:  \n"
-	latencyTag := "latency:  \n"
-	plainText += balanceTag + reportLine("balance_time", float64(last.BalanceInterval), float64(cur.BalanceInterval))
-	plainText += scheduleTag + reportLine("balance_leader_operator_count",
-		float64(last.CurBalanceLeaderCount-last.PrevBalanceLeaderCount), float64(cur.CurBalanceLeaderCount-cur.PrevBalanceLeaderCount))
-	plainText += reportLine("balance_region_operator_count",
-		float64(last.CurBalanceRegionCount-last.PrevBalanceRegionCount), float64(cur.CurBalanceRegionCount-cur.PrevBalanceRegionCount))
-	plainText += compactionTag + reportLine("compaction_flow_bytes",
-		last.CurCompactionRate-last.PrevCompactionRate, cur.CurCompactionRate-cur.PrevCompactionRate)
-	plainText += latencyTag + reportLine("prev_query_latency", last.PrevLatency, cur.PrevLatency)
-	plainText += reportLine("cur_query_latency", last.CurLatency, cur.CurLatency)
-	plainText += reportLine("prev_apply_log_latency", last.PrevApplyLog, cur.PrevApplyLog)
-	plainText += reportLine("cur_apply_log_latency", last.CurApplyLog, cur.CurApplyLog)
-	plainText += reportLine("prev_db_mutex_latency", last.PrevDbMutex, cur.PrevDbMutex)
-	plainText += reportLine("cur_db_mutex_latency", last.CurDbMutex, cur.CurDbMutex)
-	plainText += "```  \n"
-	return
+	return plainText
+}
+
+// curveReport reports the stats of each step of a stepped scale out.
+func curveReport(steps []utils.ScaleOutStep) string {
+	if len(steps) == 0 {
+		return ""
+	}
+	plainText := "scale out curve:  \n"
+	for _, step := range steps {
+		plainText += fmt.Sprintf(" \t* %d stores: balance_time %.2fs, balance_time_per_store %.2fs, region_operators %d, data_moved %.0fB, latency %.8fs  \n",
+			step.Stores, step.BalanceTime, step.BalanceTimePerStore, step.RegionOperators, step.DataMoved, step.Latency)
+	}
+	return plainText
+}
+
+// missingReport lists metrics which have no data, so that they are not mistaken for a real zero.
+func missingReport(missing []string) string {
+	if len(missing) == 0 {
+		return ""
+	}
+	plainText := "missing:  \n"
+	for _, name := range missing {
+		plainText += "\t* " + name + "  \n"
+	}
+	return plainText
 }
 
 type simulatorBench struct {
 	simPath string
 	c       *cluster
 	report  string
+	// durations is the time of each run which is not taken by sweepResult yet
+	durations []float64
 }
 
 func (s *simulatorBench) Run() error {
-	cmd := utils.NewCommand(s.simPath, s.c.pdAddr)
-	limit := os.Getenv("STORE_LIMIT")
+	cmd := utils.NewCommand(s.simPath, filepath.Base(s.simPath), s.c.pdAddr).SetLogFile(s.c.artifactPath("simulator.log"))
+	limit := s.c.storeLimit
 	if limit == "" {
 		limit = "2000"
 	}
-	ctl := utils.NewCommand("/bin/pd-ctl", "--pd", s.c.pdAddr, "store", "limit", "all", limit)
-	go func() {
-		time.Sleep(3 * time.Second)
-		_, err := ctl.Run()
-		if err != nil {
-			log.Error("pd-ctl", zap.Error(err))
+	rate, err := strconv.ParseFloat(limit, 64)
+	if err != nil {
+		return errors.Annotate(err, "invalid STORE_LIMIT")
+	}
+	s.c.SetConfig("STORE_LIMIT", limit)
+	s.c.SetConfig("simulator", s.simPath)
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// the store limit is set after the simulator registers stores
+	if err := s.setup(rate); err != nil {
+		if killErr := cmd.Kill(); killErr != nil {
+			log.Warn("failed to kill simulator", zap.Error(killErr))
 		}
-	}()
-	out, err := cmd.Run()
+		_, _ = cmd.Wait()
+		s.c.copyArtifact("simLog", "simLog")
+		return errors.Annotate(err, "failed to set up pd for simulator")
+	}
+	out, err := cmd.Wait()
+	s.c.copyArtifact("simLog", "simLog")
 	if err != nil {
 		return err
 	}
-	s.report = out
+	s.durations = append(s.durations, time.Since(start).Seconds())
+	// keep the output of all iterations
+	if s.report != "" {
+		s.report += "\n"
+	}
+	s.report += out
 	return nil
 }
 
+func (s *simulatorBench) setup(rate float64) error {
+	if err := s.c.WaitPDReady(1); err != nil {
+		return err
+	}
+	return s.c.SetAllStoreLimit(rate)
+}
+
+// sweepResult implements sweeper.
+func (s *simulatorBench) sweepResult() (interface{}, error) {
+	if len(s.durations) == 0 {
+		return nil, errors.New("no result of simulator")
+	}
+	rep := &utils.SimulatorOnce{Duration: utils.Summarize(s.durations).Mean}
+	s.durations = nil
+	return rep, nil
+}
+
 func (s *simulatorBench) Collect() error {
 	lastReport, err := s.c.GetLastReport()
 	if err != nil {
@@ -317,11 +627,14 @@ func (s *simulatorBench) Collect() error {
 	} else { //second send
 		data = createArtReport("cur", "simulator report", s.report)
 		plainText = "```diff  \n"
+		plainText += s.c.runMetadata().Header()
 		plainText += lastReport.Data
 		plainText += data
 		plainText += "```  \n"
 		log.Info("Concat report success", zap.String("concat result", plainText))
 	}
+	// simulator report is plaintext, so that there is no metric to compare
+	s.c.renderComparison(&utils.Comparison{Case: s.c.caseName(), Meta: s.c.runMetadata()})
 	return s.c.SendReport(data, plainText)
 }
 
@@ -339,7 +652,7 @@ func createSimulatorCase(cluster *cluster, simCase string) *benchCase {
 
 func createArtReport(head, note, report string) string {
 	plainText := head + ":  \n"
-	plainText += "\t*artifacts link: " + os.Getenv("ARTIFACT_URL") + "/workload.tar.gz   \n"
+	plainText += "\t*artifacts link: " + os.Getenv("ARTIFACT_URL") + "/" + utils.ArtifactBundle + "   \n"
 	plainText += "\t*" + note + "  \n"
 	plainText += report + "  \n"
 
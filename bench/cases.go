@@ -1,12 +1,21 @@
 package bench
 
+import (
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+)
+
 type benchCase struct {
 	generator
 	bench
+	// snapshots is the data of static tables after Generate, it is verified after Run
+	snapshots []*utils.TableSnapshot
 }
 
 type benchCases struct {
-	cases map[string]*benchCase
+	cluster *cluster
+	cases   map[string]*benchCase
 }
 
 // NewBenches return bench cases
@@ -14,8 +23,17 @@ func NewBenches(cluster *cluster) *benchCases {
 	caseMap := make(map[string]*benchCase)
 	caseMap["scale-out"] = createScaleOutCase(cluster)
 	caseMap["sim-import"] = createSimulatorCase(cluster, "import")
+	caseMap["pd-leader-switch"] = createPDLeaderSwitchCase(cluster)
+	caseMap["rolling-restart"] = createRollingRestartCase(cluster)
+	caseMap["tpcc"] = createTPCCCase(cluster)
+	caseMap["elasticity"] = createElasticityCase(cluster)
+	caseMap["heterogeneous-scale-out"] = createHeterogeneousCase(cluster)
+	caseMap["placement-rules"] = createPlacementCase(cluster)
+	caseMap["pd-scale-out"] = createPDScaleOutCase(cluster)
+	caseMap["tidb-scale-out"] = createTiDBScaleOutCase(cluster)
 	return &benchCases{
-		cases: caseMap,
+		cluster: cluster,
+		cases:   caseMap,
 	}
 }
 
@@ -35,3 +53,71 @@ func (c *benchCases) SupportList() []string {
 	}
 	return ret
 }
+
+// defaultYCSBWorkload is the go-ycsb workload of a case which does not load ycsb by default.
+const defaultYCSBWorkload = "workload-scale-out"
+
+// SetGenerator replaces the generator of the case, support list: ycsb, sysbench, restore, empty.
+func (c *benchCases) SetGenerator(caseName, name string) error {
+	benchCase := c.GetBench(caseName)
+	if benchCase == nil {
+		return errors.Errorf("unknown case %s", caseName)
+	}
+	switch name {
+	case "ycsb":
+		// a case which loads ycsb by default keeps its own workload
+		if _, ok := benchCase.generator.(*ycsb); !ok {
+			benchCase.generator = newYCSB(c.cluster, defaultYCSBWorkload)
+		}
+	case "sysbench":
+		benchCase.generator = newSysbench(c.cluster)
+	case "restore":
+		benchCase.generator = newRestore(c.cluster)
+	case "empty":
+		benchCase.generator = newEmptyGenerator()
+	default:
+		return errors.Errorf("unknown generator %s, support list: ycsb, sysbench, restore, empty", name)
+	}
+	return nil
+}
+
+// workload is implemented by a generator which keeps a workload running during Run.
+type workload interface {
+	StartWorkload() error
+	StopWorkload()
+}
+
+// Run runs the bench, the workload of the generator is started before it and stopped after it,
+// so that the workload runs whether data is generated in this run or not.
+func (c *benchCase) Run() error {
+	w, ok := c.generator.(workload)
+	if ok {
+		if err := w.StartWorkload(); err != nil {
+			return err
+		}
+	}
+	err := c.bench.Run()
+	if ok {
+		w.StopWorkload()
+	}
+	return err
+}
+
+// resetter is implemented by a bench which can restore the cluster between iterations.
+type resetter interface {
+	Reset() error
+}
+
+// Reset restores the cluster between iterations, a reset command takes precedence over the reset of the bench.
+// The command is run by shell, so that it can have arguments.
+func (c *benchCase) Reset(cmd string) error {
+	if cmd != "" {
+		_, err := utils.NewCommand("/bin/sh", "sh", "-c", cmd).Run()
+		return err
+	}
+	if r, ok := c.bench.(resetter); ok {
+		return r.Reset()
+	}
+	log.Warn("no reset step between iterations, the next iteration starts from current cluster")
+	return nil
+}
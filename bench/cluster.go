@@ -1,17 +1,19 @@
 package bench
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/lhy1024/bench/utils"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
-	"github.com/prometheus/client_golang/api"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
 	"go.uber.org/zap"
@@ -21,6 +23,8 @@ import (
 const (
 	resourcePrefix = "api/cluster/resource/%v"
 	scaleOutPrefix = "api/cluster/scale_out/%v/%v/%v"
+	restartPrefix  = "api/cluster/restart/%v/%v/%v"
+	scaleInPrefix  = "api/cluster/scale_in/%v/%v/%v"
 	resultsPrefix  = "api/cluster/workload/%v/result"
 )
 
@@ -33,6 +37,49 @@ type ResourceRequestItem struct {
 	RID          uint   `gorm:"column:r_id" json:"r_id"`
 	// Components records which *_servers are serving on this machine
 	Components string `gorm:"column:components" json:"components"`
+	// Labels are the labels of the machine, such as zone=z1,rack=r1,host=h1. The platform should return
+	// them in the labels column of resources, a machine without it is only selected by a selector without labels.
+	// They are not passed to TiKV by deploy, bench sets them to new stores by PD.
+	Labels string `gorm:"column:labels" json:"labels"`
+}
+
+// ResourceSelector selects the machine of a new instance, an empty field matches any machine.
+type ResourceSelector struct {
+	InstanceType string
+	Labels       map[string]string
+}
+
+// Match returns true if the machine is of the instance type and has all labels.
+func (s *ResourceSelector) Match(r *ResourceRequestItem) bool {
+	if s.InstanceType != "" && r.InstanceType != s.InstanceType {
+		return false
+	}
+	if len(s.Labels) == 0 {
+		return true
+	}
+	labels, err := utils.ParseLabels(r.Labels)
+	if err != nil {
+		log.Warn("invalid labels of resource", zap.Uint("id", r.ID), zap.Error(err))
+		return false
+	}
+	return utils.MatchLabels(labels, s.Labels)
+}
+
+// String describes the selector in errors and config.
+func (s *ResourceSelector) String() string {
+	var parts []string
+	if s.InstanceType != "" {
+		parts = append(parts, "instance_type="+s.InstanceType)
+	}
+	keys := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, k+"="+s.Labels[k])
+	}
+	return strings.Join(parts, ",")
 }
 
 func (r *ResourceRequestItem) hasNum(style string) (num int) {
@@ -61,6 +108,17 @@ type cluster struct {
 	prometheusAddr string
 	apiAddr        string
 	client         *http.Client
+	artifacts      *utils.Artifacts
+	meta           *utils.Metadata
+	storeDir       string
+	storeLimit     string
+	restorePath    string
+	formats        []string
+	threshold      float64
+
+	metricsOnce sync.Once
+	metrics     *metricClient
+	metricsErr  error
 }
 
 // NewCluster return cluster
@@ -72,7 +130,13 @@ func NewCluster() *cluster {
 		pdAddr:         os.Getenv("PD_ADDR"),
 		prometheusAddr: os.Getenv("PROM_ADDR"),
 		apiAddr:        os.Getenv("API_SERVER"),
+		storeDir:       os.Getenv("REPORT_STORE"),
+		storeLimit:     os.Getenv("STORE_LIMIT"),
+		restorePath:    os.Getenv("RESTORE_PATH"),
 		client:         &http.Client{},
+		artifacts:      utils.NewArtifacts(artifactDir()),
+		formats:        []string{"json"},
+		threshold:      utils.DefaultThreshold,
 	}
 }
 
@@ -81,6 +145,42 @@ func (c *cluster) SetAPIServer(apiAddr string) {
 	c.apiAddr = apiAddr
 }
 
+// SetPDAddr is used to set config.
+func (c *cluster) SetPDAddr(pdAddr string) {
+	c.pdAddr = pdAddr
+}
+
+// SetArtifactDir is used to set config.
+func (c *cluster) SetArtifactDir(dir string) {
+	c.artifacts = utils.NewArtifacts(dir)
+}
+
+// SetReportStore is used to set config.
+func (c *cluster) SetReportStore(dir string) {
+	c.storeDir = dir
+}
+
+// SetReportFormats is used to set config.
+func (c *cluster) SetReportFormats(formats []string) error {
+	for _, format := range formats {
+		if _, err := utils.GetRenderer(format); err != nil {
+			return err
+		}
+	}
+	c.formats = formats
+	return nil
+}
+
+// SetThreshold is used to set config.
+func (c *cluster) SetThreshold(threshold float64) {
+	c.threshold = threshold
+}
+
+// SetRestorePath sets the path of the dataset which is restored by the restore generator.
+func (c *cluster) SetRestorePath(path string) {
+	c.restorePath = path
+}
+
 // SetID is used to set config.
 func (c *cluster) SetID(id string) {
 	c.id = id
@@ -107,29 +207,41 @@ func (c *cluster) getAllResource() ([]ResourceRequestItem, error) {
 	return resources, err
 }
 
-func (c *cluster) getAvailableResourceID(component string) (uint, error) {
+// getDeployedNum returns the number of instances of the component which are deployed by the platform.
+func (c *cluster) getDeployedNum(component string) (num int, err error) {
 	resources, err := c.getAllResource()
 	if err != nil {
-		return 0, errors.New("failed to get all resource")
+		return 0, errors.Annotate(err, "failed to get all resource")
 	}
-	// select available
-	for _, resource := range resources {
-		if resource.hasNum(component) == 0 {
-			return resource.ID, nil
-		}
+	for i := range resources {
+		num += resources[i].hasNum(component)
 	}
-	return 0, errors.New("no available resources")
+	return num, nil
+}
+
+// waitDeployed waits until the platform deploys num instances of the component, as it deploys asynchronously.
+func (c *cluster) waitDeployed(component string, num int, timeout time.Duration) error {
+	return waitUntil(component+" to be deployed", timeout, time.Second, func() (bool, error) {
+		deployed, err := c.getDeployedNum(component)
+		return deployed >= num, err
+	})
 }
 
-func (c *cluster) getStoreNum() (num int) {
+func (c *cluster) getAvailableResourceID(component string, selector *ResourceSelector) (uint, error) {
 	resources, err := c.getAllResource()
 	if err != nil {
-		return 0
+		return 0, errors.New("failed to get all resource")
+	}
+	// select available
+	for i := range resources {
+		if resources[i].hasNum(component) == 0 && selector.Match(&resources[i]) {
+			return resources[i].ID, nil
+		}
 	}
-	for _, resource := range resources {
-		num += resource.hasNum("tikv")
+	if selector.String() != "" {
+		return 0, errors.Errorf("no available resources of %s", selector)
 	}
-	return num
+	return 0, errors.New("no available resources")
 }
 
 func (c *cluster) scaleOut(component string, id uint) error {
@@ -141,26 +253,66 @@ func (c *cluster) scaleOut(component string, id uint) error {
 
 // AddStore is used to add store.
 func (c *cluster) AddStore() error {
-	component := "tikv"
-	id, err := c.getAvailableResourceID(component)
+	return c.AddStoreOf(&ResourceSelector{})
+}
+
+// AddStoreOf is used to add store on a machine which is selected by selector.
+func (c *cluster) AddStoreOf(selector *ResourceSelector) error {
+	return c.addInstance(componentTiKV, selector)
+}
+
+// addInstance adds an instance of the component on a machine which is selected by selector.
+func (c *cluster) addInstance(component string, selector *ResourceSelector) error {
+	id, err := c.getAvailableResourceID(component, selector)
 	if err != nil {
 		return err
 	}
 	return c.scaleOut(component, id)
 }
 
+// Restart is used to restart an instance of the component, such as a tikv whose address is 127.0.0.1:20160.
+func (c *cluster) Restart(component, address string) error {
+	prefix := fmt.Sprintf(restartPrefix, c.id, component, url.PathEscape(address))
+	_, err := doRequest(c.joinURL(prefix), http.MethodPost)
+	return err
+}
+
+// ScaleInInstance is used to remove an instance of the component by its address and release its resource.
+func (c *cluster) ScaleInInstance(component, address string) error {
+	prefix := fmt.Sprintf(scaleInPrefix, c.id, component, url.PathEscape(address))
+	_, err := doRequest(c.joinURL(prefix), http.MethodPost)
+	return err
+}
+
 // SendReport is used to send report.
 func (c *cluster) SendReport(data, plainText string) error {
 	prefix := fmt.Sprintf(resultsPrefix, c.id)
 	url := c.joinURL(prefix)
-	return postJSON(url, map[string]interface{}{
+	report := map[string]interface{}{
 		"data":      data,
 		"plaintext": plainText,
-	})
+	}
+	c.writeArtifactJSON("report.json", report)
+	if c.storeDir != "" {
+		err := newLocalStore(c.storeDir).Save(&WorkloadReport{Data: data, PlainText: &plainText})
+		if err != nil {
+			log.Warn("failed to save report to local store", zap.String("dir", c.storeDir), zap.Error(err))
+		}
+	}
+	return postJSON(url, report)
 }
 
 // GetLastReport is used to get the last report.
 func (c *cluster) GetLastReport() (*WorkloadReport, error) {
+	reports, err := c.GetReports(1)
+	if err != nil || len(reports) == 0 {
+		return nil, err
+	}
+	return &reports[0], nil
+}
+
+// GetReports is used to get at most limit reports, the newest first. All reports are returned if limit is 0.
+func (c *cluster) GetReports(limit int) ([]WorkloadReport, error) {
 	prefix := fmt.Sprintf(resultsPrefix, c.id)
 	url := c.joinURL(prefix)
 	resp, err := doRequest(url, http.MethodGet)
@@ -170,67 +322,123 @@ func (c *cluster) GetLastReport() (*WorkloadReport, error) {
 
 	reports := make([]WorkloadReport, 0)
 	err = json.Unmarshal([]byte(resp), &reports)
-	if err != nil || len(reports) == 0 {
+	if err != nil {
 		return nil, err
 	}
-	return &reports[0], nil
+	// the order of the API response is not guaranteed
+	return newestReports(reports, limit), nil
 }
 
-func (c *cluster) getMetric(query string, t time.Time) (float64, error) {
-	client, err := api.NewClient(api.Config{
-		Address: c.prometheusAddr,
+func (c *cluster) metricClient() (*metricClient, error) {
+	c.metricsOnce.Do(func() {
+		c.metrics, c.metricsErr = newMetricClient(c.prometheusAddr)
+		if c.metricsErr != nil {
+			log.Error("error creating client", zap.Error(c.metricsErr))
+		}
 	})
-	if err != nil {
-		log.Error("error creating client", zap.Error(err))
-		return 0, err
+	return c.metrics, c.metricsErr
+}
+
+// seriesLabel picks the label which tells series apart, such as the store id or instance address.
+func seriesLabel(metric model.Metric) string {
+	for _, name := range []model.LabelName{"store", "address", "instance"} {
+		if v, ok := metric[name]; ok {
+			return string(v)
+		}
 	}
+	return metric.String()
+}
 
-	v1api := v1.NewAPI(client)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	result, warnings, err := v1api.Query(ctx, query, t)
+// getMetric returns the value of a query which is expected to have exactly one series.
+func (c *cluster) getMetric(query string, t time.Time) (float64, error) {
+	values, err := c.getLabeledMetric(query, t)
 	if err != nil {
-		log.Error("error querying Prometheus", zap.Error(err))
 		return 0, err
 	}
-	if len(warnings) > 0 {
-		log.Warn("query has warnings")
+	if len(values) > 1 {
+		return 0, errors.Errorf("query %s returns %d series, expect 1", query, len(values))
 	}
-	vector := result.(model.Vector)
-	if len(vector) >= 1 {
-		return float64(vector[0].Value), nil
+	for _, v := range values {
+		return v, nil
 	}
-	return 0, nil
+	return 0, errors.Annotate(errNoData, query)
 }
 
-func (c *cluster) getMatrixMetric(query string, r v1.Range) ([][]float64, error) {
-	client, err := api.NewClient(api.Config{
-		Address: c.prometheusAddr,
-	})
+// getLabeledMetric returns the value of every series, keyed by seriesLabel.
+func (c *cluster) getLabeledMetric(query string, t time.Time) (map[string]float64, error) {
+	client, err := c.metricClient()
 	if err != nil {
-		log.Error("error creating client", zap.Error(err))
 		return nil, err
 	}
+	result, err := client.Query(query, t)
+	if err != nil {
+		return nil, err
+	}
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return nil, errors.Errorf("query %s returns %s, expect vector", query, result.Type())
+	}
+	if len(vector) == 0 {
+		return nil, errors.Annotate(errNoData, query)
+	}
+	ret := make(map[string]float64, len(vector))
+	for _, sample := range vector {
+		ret[seriesLabel(sample.Metric)] = float64(sample.Value)
+	}
+	return ret, nil
+}
 
-	v1api := v1.NewAPI(client)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	result, warnings, err := v1api.QueryRange(ctx, query, r)
+// getMatrixMetric returns the values of every series in range, keyed by seriesLabel.
+func (c *cluster) getMatrixMetric(query string, r v1.Range) (map[string][]float64, error) {
+	client, err := c.metricClient()
+	if err != nil {
+		return nil, err
+	}
+	result, err := client.QueryRange(query, r)
 	if err != nil {
-		log.Error("error querying Prometheus", zap.Error(err))
 		return nil, err
 	}
-	if len(warnings) > 0 {
-		log.Warn("query has warnings")
+	matrix, ok := result.(model.Matrix)
+	if !ok {
+		return nil, errors.Errorf("query %s returns %s, expect matrix", query, result.Type())
+	}
+	if len(matrix) == 0 {
+		return nil, errors.Annotate(errNoData, query)
 	}
-	matrix := result.(model.Matrix)
-	var ret [][]float64
+	ret := make(map[string][]float64, len(matrix))
 	for _, m := range matrix {
 		var r []float64
 		for _, v := range m.Values {
 			r = append(r, float64(v.Value))
 		}
-		ret = append(ret, r)
+		ret[seriesLabel(m.Metric)] = r
 	}
 	return ret, nil
 }
+
+// getScrapedMetric returns the samples of every series in the window until t as they are scraped,
+// so that their timestamps are the time of scrapes. selector should be a plain series selector.
+func (c *cluster) getScrapedMetric(selector string, window time.Duration, t time.Time) (model.Matrix, error) {
+	client, err := c.metricClient()
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("%s[%ds]", selector, int64(window.Seconds()))
+	result, err := client.Query(query, t)
+	if err != nil {
+		return nil, err
+	}
+	matrix, ok := result.(model.Matrix)
+	if !ok {
+		return nil, errors.Errorf("query %s returns %s, expect matrix", query, result.Type())
+	}
+	if len(matrix) == 0 {
+		return nil, errors.Annotate(errNoData, query)
+	}
+	return matrix, nil
+}
+
+// isNoData returns true if the error is caused by an empty query result.
+func isNoData(err error) bool {
+	return errors.Cause(err) == errNoData
+}
@@ -0,0 +1,422 @@
+package bench
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/siddontang/go-mysql/client"
+)
+
+const (
+	componentTiKV = "tikv"
+	componentPD   = "pd"
+	componentTiDB = "tidb"
+
+	// componentReadyTimeout is the timeout of instances to be ready after scaling.
+	componentReadyTimeout = 10 * time.Minute
+)
+
+// ScaleOut adds n instances of the component, waits until they are ready, and returns their addresses:
+// tikv stores are up, pd members serve with a leader, and tidb status ports respond.
+func (c *cluster) ScaleOut(component string, n int) ([]string, error) {
+	switch component {
+	case componentTiKV:
+		added, err := c.scaleOutStores(n)
+		if err != nil {
+			return nil, err
+		}
+		addresses := make([]string, 0, len(added.stores))
+		for _, store := range added.stores {
+			addresses = append(addresses, store.Address)
+		}
+		return addresses, nil
+	case componentPD:
+		before, err := c.getPDAddresses()
+		if err != nil {
+			return nil, err
+		}
+		if err := c.addInstances(component, n); err != nil {
+			return nil, err
+		}
+		if err := c.waitPDMembers(len(before) + n); err != nil {
+			return nil, err
+		}
+		after, err := c.getPDAddresses()
+		if err != nil {
+			return nil, err
+		}
+		return newAddresses(before, after, n), nil
+	case componentTiDB:
+		before, err := c.getTiDBAddresses()
+		if err != nil {
+			return nil, err
+		}
+		if err := c.addInstances(component, n); err != nil {
+			return nil, err
+		}
+		if err := c.waitTiDBInstances(len(before) + n); err != nil {
+			return nil, err
+		}
+		after, err := c.getTiDBAddresses()
+		if err != nil {
+			return nil, err
+		}
+		return newAddresses(before, after, n), nil
+	default:
+		return nil, errors.Errorf("unknown component %s, support list: %s,%s,%s", component, componentTiKV, componentPD, componentTiDB)
+	}
+}
+
+// addedStores is the stores which are added by scaleOutStores.
+type addedStores struct {
+	stores []utils.StoreStats
+	// deploy is the time until the platform deploys the stores, register is the time from then until they are up in PD.
+	deploy, register float64
+}
+
+// scaleOutStores adds n stores, and waits until the platform deploys them and they are up in PD.
+// It is the tikv part of ScaleOut, the scale-out case also reports the time of each phase.
+func (c *cluster) scaleOutStores(n int) (*addedStores, error) {
+	before, err := c.getStoreStats()
+	if err != nil {
+		return nil, err
+	}
+	deployed, err := c.getDeployedNum(componentTiKV)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	if err := c.addInstances(componentTiKV, n); err != nil {
+		return nil, err
+	}
+	if err := c.waitDeployed(componentTiKV, deployed+n, storeRegisterTimeout()); err != nil {
+		return nil, err
+	}
+	added := &addedStores{deploy: time.Since(start).Seconds()}
+	start = time.Now()
+	if added.stores, err = c.waitNewStores(before, n, storeRegisterTimeout()); err != nil {
+		return nil, errors.Annotatef(err, "%d deployed stores are not up in pd", n)
+	}
+	added.register = time.Since(start).Seconds()
+	return added, nil
+}
+
+// ScaleIn removes the instances of the component at the addresses, which are usually returned by ScaleOut,
+// and waits until the rest are ready. tikv stores are deleted until tombstone.
+func (c *cluster) ScaleIn(component string, addresses []string) error {
+	switch component {
+	case componentTiKV:
+		stores, err := c.getStoreStats()
+		if err != nil {
+			return err
+		}
+		var up, removed []utils.StoreStats
+		for _, store := range stores {
+			if store.State != utils.StoreUp {
+				continue
+			}
+			up = append(up, store)
+			if containsAddress(addresses, store.Address) {
+				removed = append(removed, store)
+			}
+		}
+		if len(removed) != len(addresses) {
+			return errors.Errorf("stores of %s are not all up", strings.Join(addresses, ","))
+		}
+		if len(up) <= len(removed) {
+			return errors.Errorf("cannot scale in %d of %d stores", len(removed), len(up))
+		}
+		return c.removeStores(removed, componentReadyTimeout)
+	case componentPD:
+		members, err := c.getPDAddresses()
+		if err != nil {
+			return err
+		}
+		if err := checkScaleIn(component, members, addresses); err != nil {
+			return err
+		}
+		if err := c.scaleInInstances(component, addresses); err != nil {
+			return err
+		}
+		return c.waitPDMembers(len(members) - len(addresses))
+	case componentTiDB:
+		instances, err := c.getTiDBAddresses()
+		if err != nil {
+			return err
+		}
+		if err := checkScaleIn(component, instances, addresses); err != nil {
+			return err
+		}
+		if containsAddress(addresses, c.tidbAddr) {
+			return errors.Errorf("cannot scale in tidb %s which is used by bench", c.tidbAddr)
+		}
+		if err := c.scaleInInstances(component, addresses); err != nil {
+			return err
+		}
+		return c.waitTiDBInstances(len(instances) - len(addresses))
+	default:
+		return errors.Errorf("unknown component %s, support list: %s,%s,%s", component, componentTiKV, componentPD, componentTiDB)
+	}
+}
+
+// ScaleInNewest removes the n newest instances of the component by ScaleIn, instances which the cluster
+// or bench depends on are kept: the pd leader and the tidb used by bench.
+func (c *cluster) ScaleInNewest(component string, n int) error {
+	var candidates []string
+	switch component {
+	case componentTiKV:
+		stores, err := c.getStoreStats()
+		if err != nil {
+			return err
+		}
+		// store ids are allocated in ascending order
+		sort.Slice(stores, func(i, j int) bool { return stores[i].ID > stores[j].ID })
+		for _, store := range stores {
+			if store.State == utils.StoreUp {
+				candidates = append(candidates, store.Address)
+			}
+		}
+	case componentPD:
+		leader, err := c.getPDLeader()
+		if err != nil {
+			return err
+		}
+		instances, err := c.getNewestInstances(component)
+		if err != nil {
+			return err
+		}
+		for _, address := range instances {
+			if address != memberAddress(leader) {
+				candidates = append(candidates, address)
+			}
+		}
+	case componentTiDB:
+		instances, err := c.getNewestInstances(component)
+		if err != nil {
+			return err
+		}
+		for _, address := range instances {
+			if address != c.tidbAddr {
+				candidates = append(candidates, address)
+			}
+		}
+	default:
+		return errors.Errorf("unknown component %s, support list: %s,%s,%s", component, componentTiKV, componentPD, componentTiDB)
+	}
+	if len(candidates) < n {
+		return errors.Errorf("%d %s instances can be scaled in, want %d", len(candidates), component, n)
+	}
+	return c.ScaleIn(component, candidates[:n])
+}
+
+// getNewestInstances returns the addresses of instances of the component which are registered in the cluster, the newest first.
+func (c *cluster) getNewestInstances(component string) ([]string, error) {
+	conn, err := client.Connect(c.tidbAddr, "root", "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	res, err := conn.Execute(fmt.Sprintf("SELECT INSTANCE FROM information_schema.cluster_info WHERE TYPE = '%s' ORDER BY START_TIME DESC, INSTANCE DESC", component))
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]string, 0, res.RowNumber())
+	for i := 0; i < res.RowNumber(); i++ {
+		address, err := res.GetString(i, 0)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// checkScaleIn checks that the addresses are instances, and at least one instance is left.
+func checkScaleIn(component string, instances, addresses []string) error {
+	for _, address := range addresses {
+		if !containsAddress(instances, address) {
+			return errors.Errorf("%s %s is not found", component, address)
+		}
+	}
+	if len(instances) <= len(addresses) {
+		return errors.Errorf("cannot scale in %d of %d %s instances", len(addresses), len(instances), component)
+	}
+	return nil
+}
+
+// newAddresses returns at most n sorted addresses which are in after but not in before.
+func newAddresses(before, after []string, n int) []string {
+	var added []string
+	for _, address := range after {
+		if !containsAddress(before, address) {
+			added = append(added, address)
+		}
+	}
+	sort.Strings(added)
+	if len(added) > n {
+		added = added[:n]
+	}
+	return added
+}
+
+func containsAddress(addresses []string, address string) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cluster) addInstances(component string, n int) error {
+	for i := 0; i < n; i++ {
+		if err := c.addInstance(component, &ResourceSelector{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *cluster) scaleInInstances(component string, addresses []string) error {
+	for _, address := range addresses {
+		if err := c.ScaleInInstance(component, address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteStores deletes stores by PD and waits until they are tombstone.
+func (c *cluster) deleteStores(stores []utils.StoreStats, timeout time.Duration) error {
+	for _, store := range stores {
+		if err := c.DeleteStore(store.ID); err != nil {
+			return err
+		}
+	}
+	return waitUntil("deleted stores to be tombstone", timeout, time.Second, func() (bool, error) {
+		for _, store := range stores {
+			s, err := c.getStore(store.ID)
+			if err != nil {
+				return false, err
+			}
+			if s.Store.StateName != utils.StoreTombstone {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+// removeStores deletes stores until they are tombstone, then releases their resources and removes the tombstones.
+func (c *cluster) removeStores(stores []utils.StoreStats, timeout time.Duration) error {
+	if err := c.deleteStores(stores, timeout); err != nil {
+		return err
+	}
+	for _, store := range stores {
+		if err := c.ScaleInInstance(componentTiKV, store.Address); err != nil {
+			return err
+		}
+	}
+	return c.RemoveTombstone()
+}
+
+// memberAddress returns the address of the first client url of a pd member.
+func memberAddress(m *PDMember) string {
+	if len(m.ClientUrls) == 0 {
+		return ""
+	}
+	addr := m.ClientUrls[0]
+	addr = strings.TrimPrefix(addr, "http://")
+	return strings.TrimPrefix(addr, "https://")
+}
+
+// getPDAddresses returns the addresses of pd members.
+func (c *cluster) getPDAddresses() ([]string, error) {
+	members, err := c.getPDMembers()
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]string, 0, len(members.Members))
+	for _, m := range members.Members {
+		addresses = append(addresses, memberAddress(m))
+	}
+	return addresses, nil
+}
+
+// waitPDMembers waits until there are num pd members and a leader.
+func (c *cluster) waitPDMembers(num int) error {
+	return waitUntil("pd members to be ready", componentReadyTimeout, time.Second, func() (bool, error) {
+		members, err := c.getPDMembers()
+		if err != nil {
+			// the members API fails while a member joins or leaves
+			return false, nil
+		}
+		return len(members.Members) == num && members.Leader != nil && members.Leader.Name != "", nil
+	})
+}
+
+// tidbInstance is a tidb server in the cluster.
+type tidbInstance struct {
+	address       string
+	statusAddress string
+}
+
+// getTiDBInstances returns the tidb servers which are registered in the cluster.
+func (c *cluster) getTiDBInstances() ([]tidbInstance, error) {
+	conn, err := client.Connect(c.tidbAddr, "root", "", "")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	res, err := conn.Execute("SELECT INSTANCE, STATUS_ADDRESS FROM information_schema.cluster_info WHERE TYPE = 'tidb' ORDER BY INSTANCE")
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]tidbInstance, 0, res.RowNumber())
+	for i := 0; i < res.RowNumber(); i++ {
+		address, err := res.GetString(i, 0)
+		if err != nil {
+			return nil, err
+		}
+		statusAddress, err := res.GetString(i, 1)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, tidbInstance{address: address, statusAddress: statusAddress})
+	}
+	return instances, nil
+}
+
+// getTiDBAddresses returns the addresses of tidb instances.
+func (c *cluster) getTiDBAddresses() ([]string, error) {
+	instances, err := c.getTiDBInstances()
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		addresses = append(addresses, instance.address)
+	}
+	return addresses, nil
+}
+
+// waitTiDBInstances waits until there are num tidb instances and their status ports respond.
+func (c *cluster) waitTiDBInstances(num int) error {
+	return waitUntil("tidb instances to be ready", componentReadyTimeout, time.Second, func() (bool, error) {
+		instances, err := c.getTiDBInstances()
+		if err != nil || len(instances) != num {
+			return false, nil
+		}
+		for _, instance := range instances {
+			if _, err := doRequest("http://"+instance.statusAddress+"/status", http.MethodGet); err != nil {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
@@ -0,0 +1,227 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+const (
+	// elasticTimeout is the timeout of each direction of a cycle.
+	elasticTimeout = 30 * time.Minute
+	operatorsQuery = "sum(pd_schedule_operators_count{event=\"finish\"})"
+)
+
+func createElasticityCase(cluster *cluster) *benchCase {
+	return &benchCase{
+		generator: newYCSB(cluster, "workload-scale-out"),
+		bench:     newElasticity(cluster),
+	}
+}
+
+// elasticity scales out by num stores and scales in by the same stores for cycles,
+// just like a cluster with autoscaling.
+type elasticity struct {
+	c         *cluster
+	num       int
+	cycles    int
+	tolerance float64
+	results   []*utils.ElasticityOnce
+}
+
+func newElasticity(c *cluster) bench {
+	num, err := strconv.Atoi(os.Getenv("SCALE_NUM"))
+	if err != nil {
+		num = 1 // default
+	}
+	cycles, err := strconv.Atoi(os.Getenv("ELASTIC_CYCLES"))
+	if err != nil {
+		cycles = 1 // default
+	}
+	tolerance, err := strconv.ParseFloat(os.Getenv("BALANCE_TOLERANCE"), 64)
+	if err != nil {
+		tolerance = utils.DefaultBalanceTolerance
+	}
+	return &elasticity{c: c, num: num, cycles: cycles, tolerance: tolerance}
+}
+
+func (e *elasticity) Run() error {
+	e.c.SetConfig("SCALE_NUM", strconv.Itoa(e.num))
+	e.c.SetConfig("ELASTIC_CYCLES", strconv.Itoa(e.cycles))
+	e.c.SetConfig("BALANCE_TOLERANCE", strconv.FormatFloat(e.tolerance, 'f', -1, 64))
+	rep := &utils.ElasticityOnce{Cycles: make(map[string]utils.ElasticityCycle)}
+	for i := 1; i <= e.cycles; i++ {
+		cycle, err := e.runCycle(rep)
+		if err != nil {
+			return errors.Annotatef(err, "failed in cycle %d", i)
+		}
+		rep.Cycles[strconv.Itoa(i)] = *cycle
+		log.Info("elasticity cycle finished", zap.Int("cycle", i), zap.Any("stats", cycle))
+	}
+	// all stats are the mean of cycles, so that runs with different cycles are comparable
+	n := float64(len(rep.Cycles))
+	for _, cycle := range rep.Cycles {
+		rep.ScaleOutBalance += cycle.ScaleOutBalance / n
+		rep.ScaleInDrain += cycle.ScaleInDrain / n
+		rep.ScaleInBalance += cycle.ScaleInBalance / n
+	}
+	rep.ScaleOutOperators /= n
+	rep.ScaleInOperators /= n
+	rep.LeftoverOperators /= n
+	if rep.ScaleOutBalance > 0 {
+		rep.Asymmetry = rep.ScaleInBalance / rep.ScaleOutBalance
+	}
+	e.results = append(e.results, rep)
+	return nil
+}
+
+// runCycle scales out, waits for balance, then deletes the new stores and waits for balance again.
+func (e *elasticity) runCycle(rep *utils.ElasticityOnce) (*utils.ElasticityCycle, error) {
+	cycle := &utils.ElasticityCycle{}
+	before, err := e.c.getStoreStats()
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	for i := 0; i < e.num; i++ {
+		if err := e.c.AddStore(); err != nil {
+			return nil, err
+		}
+	}
+	added, err := e.c.waitNewStores(before, e.num, elasticTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.waitBalance(rep); err != nil {
+		return nil, err
+	}
+	cycle.ScaleOutBalance = time.Since(start).Seconds()
+	rep.ScaleOutOperators += float64(e.operatorCount(rep, "ScaleOutOperators", start))
+
+	start = time.Now()
+	if err := e.c.deleteStores(added, elasticTimeout); err != nil {
+		return nil, err
+	}
+	cycle.ScaleInDrain = time.Since(start).Seconds()
+	// the resources are released, so that the next cycle can scale out again
+	for _, store := range added {
+		if err := e.c.ScaleInInstance("tikv", store.Address); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.c.RemoveTombstone(); err != nil {
+		return nil, err
+	}
+	if err := e.waitBalance(rep); err != nil {
+		return nil, err
+	}
+	cycle.ScaleInBalance = time.Since(start).Seconds()
+	rep.ScaleInOperators += float64(e.operatorCount(rep, "ScaleInOperators", start))
+	return cycle, nil
+}
+
+// waitBalance waits until scores of up stores are balanced, the operators which are still pending
+// then are leftovers, they are not waited for, so that a cycle is not hidden by them.
+func (e *elasticity) waitBalance(rep *utils.ElasticityOnce) error {
+	var stores []utils.StoreStats
+	err := waitUntil("stores to be balanced", elasticTimeout, time.Second, func() (bool, error) {
+		var err error
+		if stores, err = e.c.getStoreStats(); err != nil {
+			return false, err
+		}
+		return utils.IsStoreBalanced(stores, e.tolerance), nil
+	})
+	if err != nil {
+		return err
+	}
+	operators, err := e.c.getOperators()
+	if err != nil {
+		return err
+	}
+	if len(operators) > 0 {
+		log.Warn("operators are left when balanced", zap.Int("count", len(operators)))
+	}
+	rep.LeftoverOperators += float64(len(operators))
+	return nil
+}
+
+// finishedOperators returns the count of operators finished since start.
+func (c *cluster) finishedOperators(start time.Time) (int, error) {
+	prev, err := c.getMetric(operatorsQuery, start)
+	if err != nil {
+		return 0, err
+	}
+	cur, err := c.getMetric(operatorsQuery, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return int(cur - prev), nil
+}
+
+// operatorCount returns the count of operators finished since start, it is 0 and recorded as missing if there is no data.
+func (e *elasticity) operatorCount(rep *utils.ElasticityOnce, name string, start time.Time) int {
+	count, err := e.c.finishedOperators(start)
+	if err == nil {
+		return count
+	}
+	if !isNoData(err) {
+		log.Warn("failed to get operator count", zap.String("name", name), zap.Error(err))
+	}
+	rep.Missing = utils.UnionMissing(rep.Missing, []string{name})
+	return 0
+}
+
+// directionReport reports the two directions of each cycle side by side.
+func directionReport(rep *utils.ElasticityOnce) string {
+	plainText := "directions (mean of cycles):  \n"
+	plainText += fmt.Sprintf(" \t* scale out: balance %.2fs, operators %.1f  \n", rep.ScaleOutBalance, rep.ScaleOutOperators)
+	plainText += fmt.Sprintf(" \t* scale in: drain %.2fs, balance %.2fs, operators %.1f  \n", rep.ScaleInDrain, rep.ScaleInBalance, rep.ScaleInOperators)
+	plainText += fmt.Sprintf(" \t* asymmetry: %.2f, leftover operators: %.1f  \n", rep.Asymmetry, rep.LeftoverOperators)
+	for i := 1; i <= len(rep.Cycles); i++ {
+		cycle := rep.Cycles[strconv.Itoa(i)]
+		plainText += fmt.Sprintf(" \t* cycle %d: scale out %.2fs, scale in %.2fs  \n", i, cycle.ScaleOutBalance, cycle.ScaleInBalance)
+	}
+	return plainText
+}
+
+func (e *elasticity) aggregate() (*utils.ElasticityOnce, error) {
+	agg, err := utils.Aggregate(e.results)
+	if err != nil {
+		return nil, err
+	}
+	rep := agg.(*utils.ElasticityOnce)
+	// cycles of the last iteration are kept as the trend
+	rep.Cycles = e.results[len(e.results)-1].Cycles
+	return rep, nil
+}
+
+func (e *elasticity) Collect() error {
+	if len(e.results) == 0 {
+		return errors.New("no result to report")
+	}
+	rep, err := e.aggregate()
+	if err != nil {
+		return err
+	}
+	rep.Meta = e.c.runMetadata()
+	return e.c.collectReport(rep, rep.Meta, directionReport(rep), missingReport(rep.Missing))
+}
+
+// sweepResult implements sweeper.
+func (e *elasticity) sweepResult() (interface{}, error) {
+	if len(e.results) == 0 {
+		return nil, errors.New("no result to report")
+	}
+	rep, err := e.aggregate()
+	if err != nil {
+		return nil, err
+	}
+	e.results = nil
+	return rep, nil
+}
@@ -0,0 +1,208 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+const (
+	// heteroTimeout is the timeout of new stores to be up and balanced.
+	heteroTimeout = 30 * time.Minute
+	// defaultCapacityTolerance is looser than the balance tolerance of scores, as the used space is not only regions.
+	defaultCapacityTolerance = 0.2
+)
+
+func createHeterogeneousCase(cluster *cluster) *benchCase {
+	return &benchCase{
+		generator: newYCSB(cluster, "workload-scale-out"),
+		bench:     newHeterogeneous(cluster),
+	}
+}
+
+// heterogeneous scales out by stores on machines of an instance type or with labels,
+// and verifies that PD balances stores according to their capacity and weight.
+type heterogeneous struct {
+	c            *cluster
+	num          int
+	instanceType string
+	labels       string
+	// regionWeight is set to new stores if it is not 0
+	regionWeight float64
+	// capacityTolerance is the max spread of used ratios of stores after balance
+	capacityTolerance float64
+	results           []*utils.HeterogeneousOnce
+	// stores is the stats of stores after the last run
+	stores []utils.StoreStats
+}
+
+func newHeterogeneous(c *cluster) bench {
+	num, err := strconv.Atoi(os.Getenv("SCALE_NUM"))
+	if err != nil {
+		num = 1 // default
+	}
+	regionWeight, err := strconv.ParseFloat(os.Getenv("HETERO_REGION_WEIGHT"), 64)
+	if err != nil {
+		regionWeight = 0
+	}
+	capacityTolerance, err := strconv.ParseFloat(os.Getenv("HETERO_CAPACITY_TOLERANCE"), 64)
+	if err != nil {
+		capacityTolerance = defaultCapacityTolerance
+	}
+	return &heterogeneous{
+		c:                 c,
+		num:               num,
+		instanceType:      os.Getenv("HETERO_INSTANCE_TYPE"),
+		labels:            os.Getenv("HETERO_LABELS"),
+		regionWeight:      regionWeight,
+		capacityTolerance: capacityTolerance,
+	}
+}
+
+func (h *heterogeneous) Run() error {
+	labels, err := utils.ParseLabels(h.labels)
+	if err != nil {
+		return errors.Annotate(err, "invalid HETERO_LABELS")
+	}
+	selector := &ResourceSelector{InstanceType: h.instanceType, Labels: labels}
+	h.c.SetConfig("SCALE_NUM", strconv.Itoa(h.num))
+	h.c.SetConfig("HETERO_CAPACITY_TOLERANCE", strconv.FormatFloat(h.capacityTolerance, 'f', -1, 64))
+	h.c.SetConfig("selector", selector.String())
+	if h.regionWeight != 0 {
+		h.c.SetConfig("HETERO_REGION_WEIGHT", strconv.FormatFloat(h.regionWeight, 'f', -1, 64))
+	}
+
+	before, err := h.c.getStoreStats()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	for i := 0; i < h.num; i++ {
+		if err := h.c.AddStoreOf(selector); err != nil {
+			return err
+		}
+	}
+	added, err := h.c.waitNewStores(before, h.num, heteroTimeout)
+	if err != nil {
+		return err
+	}
+	if err := h.c.labelStores(added, labels); err != nil {
+		return err
+	}
+	if h.regionWeight != 0 {
+		for _, store := range added {
+			if err := h.c.SetStoreWeight(store.ID, 1, h.regionWeight); err != nil {
+				return err
+			}
+		}
+	}
+	// the run fails if stores are not balanced according to capacity, the failure is recorded on the report
+	balanceErr := waitUntil("stores to be balanced according to capacity", heteroTimeout, time.Second, h.isBalanced)
+	rep := &utils.HeterogeneousOnce{BalanceTime: time.Since(start).Seconds()}
+	if h.stores, err = h.c.getStoreStats(); err != nil {
+		return err
+	}
+	rep.Stores = utils.CapacityStores(h.stores)
+	var scores, normalized []float64
+	for _, store := range rep.Stores {
+		scores = append(scores, store.RegionScore)
+		normalized = append(normalized, store.NormalizedScore)
+	}
+	rep.RegionScoreSpread = utils.Spread(scores)
+	rep.NormalizedScoreSpread = utils.Spread(normalized)
+	rep.UsedRatioSpread = utils.UsedRatioSpread(h.stores)
+	h.results = append(h.results, rep)
+	if balanceErr != nil {
+		h.c.SetError(errors.Annotatef(balanceErr, "stores are not balanced according to capacity, spread of used ratios is %.2f, tolerance is %.2f",
+			rep.UsedRatioSpread, h.capacityTolerance))
+		log.Warn("heterogeneous stores are not balanced", zap.Any("stats", rep), zap.Error(balanceErr))
+		return nil
+	}
+	log.Info("heterogeneous stores are balanced", zap.Any("stats", rep))
+	return nil
+}
+
+// isBalanced returns true if no operator is pending and used ratios of stores are even. PD divides region scores
+// by weight, so that weights proportional to capacity make used ratios even, and equal region scores do not.
+func (h *heterogeneous) isBalanced() (bool, error) {
+	operators, err := h.c.getOperators()
+	if err != nil || len(operators) > 0 {
+		return false, err
+	}
+	stores, err := h.c.getStoreStats()
+	if err != nil {
+		return false, err
+	}
+	return utils.UsedRatioSpread(stores) <= h.capacityTolerance, nil
+}
+
+// labelReport reports the labels and capacity of each up store.
+func labelReport(stores []utils.StoreStats) string {
+	if len(stores) == 0 {
+		return ""
+	}
+	plainText := "stores:  \n"
+	for _, store := range stores {
+		if store.State != utils.StoreUp {
+			continue
+		}
+		labels := make([]string, 0, len(store.Labels))
+		for k, v := range store.Labels {
+			labels = append(labels, k+"="+v)
+		}
+		sort.Strings(labels)
+		plainText += fmt.Sprintf(" \t* store_%d: capacity %.2fGiB, region_weight %.2f, region_score %.2f, labels %s  \n",
+			store.ID, float64(store.Capacity)/(1<<30), store.RegionWeight, store.RegionScore, strings.Join(labels, ","))
+	}
+	return plainText
+}
+
+func (h *heterogeneous) aggregate() (*utils.HeterogeneousOnce, error) {
+	agg, err := utils.Aggregate(h.results)
+	if err != nil {
+		return nil, err
+	}
+	rep := agg.(*utils.HeterogeneousOnce)
+	// stores may differ between iterations, so that only the last one is kept
+	rep.Stores = h.results[len(h.results)-1].Stores
+	return rep, nil
+}
+
+func (h *heterogeneous) Collect() error {
+	if len(h.results) == 0 {
+		return errors.New("no result to report")
+	}
+	rep, err := h.aggregate()
+	if err != nil {
+		return err
+	}
+	rep.Meta = h.c.runMetadata()
+	if err := h.c.collectReport(rep, rep.Meta, labelReport(h.stores)); err != nil {
+		return err
+	}
+	if rep.Meta.Error != "" {
+		return errors.New(rep.Meta.Error)
+	}
+	return nil
+}
+
+// sweepResult implements sweeper.
+func (h *heterogeneous) sweepResult() (interface{}, error) {
+	if len(h.results) == 0 {
+		return nil, errors.New("no result to report")
+	}
+	rep, err := h.aggregate()
+	if err != nil {
+		return nil, err
+	}
+	h.results = nil
+	return rep, nil
+}
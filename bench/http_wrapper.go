@@ -3,6 +3,7 @@ package bench
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -56,6 +57,16 @@ func doRequest(url, method string,
 	return resp, nil
 }
 
+// statusError is returned if the server does not respond 200.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("[%d] %s", e.code, e.msg)
+}
+
 func dial(req *http.Request) (string, error) {
 	resp, err := dialClient.Do(req)
 	if err != nil {
@@ -68,7 +79,7 @@ func dial(req *http.Request) (string, error) {
 		if err != nil {
 			return "", err
 		}
-		return "", errors.Errorf("[%d] %s", resp.StatusCode, msg)
+		return "", errors.WithStack(&statusError{code: resp.StatusCode, msg: string(msg)})
 	}
 
 	content, err := ioutil.ReadAll(resp.Body)
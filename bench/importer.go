@@ -39,13 +39,14 @@ func splitAddr(addr string) (host string, port string, err error) {
 
 // Generate is used to generate data.
 func (l *ycsb) Generate() error {
+	l.c.SetConfig("workload", l.workload)
 	host, port, err := splitAddr(l.c.tidbAddr)
 	if err != nil {
 		return err
 	}
 	// go-ycsb insert
-	cmd := utils.NewCommand("./go-ycsb/go-ycsb", "load", "mysql", "-P", "./go-ycsb/"+l.workload, "-p", "mysql.user=root", "-p", "mysql.db="+l.dbName,
-		"-p", "mysql.host="+host, "-p", "mysql.port="+port)
+	cmd := utils.NewCommand("./go-ycsb/go-ycsb", "go-ycsb", "load", "mysql", "-P", "./go-ycsb/"+l.workload, "-p", "mysql.user=root", "-p", "mysql.db="+l.dbName,
+		"-p", "mysql.host="+host, "-p", "mysql.port="+port).SetLogFile(l.c.artifactPath("go-ycsb-load.log"))
 	_, err = cmd.Run()
 	if err != nil {
 		return err
@@ -77,6 +78,11 @@ func (l *ycsb) split() error {
 	return nil
 }
 
+// checkTables implements checker.
+func (l *ycsb) checkTables() ([]string, bool) {
+	return []string{l.dbName + ".test_go_ycsb"}, true
+}
+
 func newEmptyGenerator() generator {
 	return &emptyGenerator{}
 }
@@ -0,0 +1,101 @@
+package bench
+
+import (
+	"time"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/log"
+	"github.com/siddontang/go-mysql/client"
+	"go.uber.org/zap"
+)
+
+// BeginRun starts the metadata of a run.
+func (c *cluster) BeginRun(caseName string) {
+	c.meta = utils.NewMetadata(caseName)
+}
+
+// SetConfig records a case config in metadata, reports with different config are not comparable.
+func (c *cluster) SetConfig(key, value string) {
+	if c.meta == nil {
+		c.meta = utils.NewMetadata("")
+	}
+	c.meta.Config[key] = value
+}
+
+// SetError marks the run as failed, the error is shown in the report.
+func (c *cluster) SetError(err error) {
+	if c.meta == nil {
+		c.meta = utils.NewMetadata("")
+	}
+	c.meta.Error = err.Error()
+}
+
+func (c *cluster) caseName() string {
+	if c.meta == nil {
+		return ""
+	}
+	return c.meta.Case
+}
+
+// runMetadata refreshes versions and topology of the cluster and returns the metadata.
+// Failures are only logged, so that a report is not lost for the metadata.
+func (c *cluster) runMetadata() *utils.Metadata {
+	if c.meta == nil {
+		c.meta = utils.NewMetadata("")
+	}
+	m := c.meta
+	m.EndTime = time.Now()
+	if version, err := c.getPDVersion(); err == nil {
+		m.Versions["pd"] = version
+	} else {
+		log.Warn("failed to get pd version", zap.Error(err))
+	}
+	if members, err := c.getPDMembers(); err == nil {
+		m.Topology["pd"] = len(members.Members)
+	} else {
+		log.Warn("failed to get pd members", zap.Error(err))
+	}
+	if stores, err := c.getStores(); err == nil {
+		m.Topology["tikv"] = len(stores)
+		for _, store := range stores {
+			if store.Store.Version != "" {
+				m.Versions["tikv"] = store.Store.Version
+				break
+			}
+		}
+	} else {
+		log.Warn("failed to get stores", zap.Error(err))
+	}
+	if metrics, err := c.metricClient(); err == nil {
+		m.MetricWarnings = metrics.Warnings()
+	}
+	if err := c.recordPDConfig(); err != nil {
+		log.Warn("failed to get pd config", zap.Error(err))
+	}
+	if c.tidbAddr != "" {
+		if version, err := c.getTiDBVersion(); err == nil {
+			m.Versions["tidb"] = version
+		} else {
+			log.Warn("failed to get tidb version", zap.Error(err))
+		}
+		if instances, err := c.getTiDBInstances(); err == nil {
+			m.Topology["tidb"] = len(instances)
+		} else {
+			log.Warn("failed to get tidb instances", zap.Error(err))
+		}
+	}
+	return m
+}
+
+func (c *cluster) getTiDBVersion() (string, error) {
+	conn, err := client.Connect(c.tidbAddr, "root", "", "")
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	res, err := conn.Execute("select tidb_version();")
+	if err != nil {
+		return "", err
+	}
+	return res.GetString(0, 0)
+}
@@ -0,0 +1,174 @@
+package bench
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/prometheus/client_golang/api"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMetricTimeout = 10 * time.Second
+	defaultMetricRetry   = 5
+	defaultMetricBackoff = 500 * time.Millisecond
+	maxMetricBackoff     = 8 * time.Second
+	// maxMetricRecords is the max number of distinct queries which are dumped to artifacts.
+	maxMetricRecords = 1000
+)
+
+var errNoData = errors.New("metric has no data")
+
+// metricRecord is a raw query result, it is dumped to artifacts.
+type metricRecord struct {
+	Query  string      `json:"query"`
+	Time   *time.Time  `json:"time,omitempty"`
+	Range  *v1.Range   `json:"range,omitempty"`
+	Result model.Value `json:"result"`
+	// Warnings is returned by prometheus with the result, such as a partial response.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// metricClient is a shared Prometheus client, it retries transient errors with backoff.
+type metricClient struct {
+	api     v1.API
+	timeout time.Duration
+	retry   int
+	backoff time.Duration
+
+	sync.Mutex
+	// records keeps the last result of each query, as a query is repeated when polled
+	records   []metricRecord
+	indexes   map[string]int
+	truncated bool
+	// warnings is the count of warnings of all queries, including the ones which are not dumped
+	warnings int
+}
+
+func newMetricClient(addr string) (*metricClient, error) {
+	client, err := api.NewClient(api.Config{
+		Address:      addr,
+		RoundTripper: api.DefaultRoundTripper,
+	})
+	if err != nil {
+		return nil, err
+	}
+	timeout := defaultMetricTimeout
+	if sec, err := strconv.Atoi(os.Getenv("PROM_TIMEOUT")); err == nil && sec > 0 {
+		timeout = time.Duration(sec) * time.Second
+	}
+	retry := defaultMetricRetry
+	if n, err := strconv.Atoi(os.Getenv("PROM_RETRY")); err == nil && n >= 0 {
+		retry = n
+	}
+	return &metricClient{
+		api:     v1.NewAPI(client),
+		timeout: timeout,
+		retry:   retry,
+		backoff: defaultMetricBackoff,
+		indexes: make(map[string]int),
+	}, nil
+}
+
+// isRetryable returns false for errors which can not be fixed by retrying, such as a bad query.
+func isRetryable(err error) bool {
+	if e, ok := err.(*v1.Error); ok {
+		return e.Type != v1.ErrBadData && e.Type != v1.ErrClient
+	}
+	return true
+}
+
+// do runs a query with retries, it returns the warnings of the last attempt.
+func (m *metricClient) do(query string, f func(ctx context.Context) (model.Value, v1.Warnings, error)) (model.Value, v1.Warnings, error) {
+	backoff := m.backoff
+	var lastErr error
+	for i := 0; i <= m.retry; i++ {
+		if i > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > maxMetricBackoff {
+				backoff = maxMetricBackoff
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+		result, warnings, err := f(ctx)
+		cancel()
+		if len(warnings) > 0 {
+			log.Warn("prometheus query has warnings", zap.String("query", query), zap.Strings("warnings", warnings))
+			m.Lock()
+			m.warnings += len(warnings)
+			m.Unlock()
+		}
+		if err == nil {
+			return result, warnings, nil
+		}
+		lastErr = err
+		if !isRetryable(err) {
+			break
+		}
+		log.Warn("error querying prometheus, retrying", zap.String("query", query), zap.Int("attempt", i+1), zap.Error(err))
+	}
+	log.Error("error querying prometheus", zap.String("query", query), zap.Error(lastErr))
+	return nil, nil, errors.Annotatef(lastErr, "query %s", query)
+}
+
+func (m *metricClient) record(r metricRecord) {
+	m.Lock()
+	defer m.Unlock()
+	if i, ok := m.indexes[r.Query]; ok {
+		m.records[i] = r
+		return
+	}
+	if len(m.records) >= maxMetricRecords {
+		if !m.truncated {
+			m.truncated = true
+			log.Warn("too many metric queries, the rest are not dumped", zap.Int("max", maxMetricRecords))
+		}
+		return
+	}
+	m.indexes[r.Query] = len(m.records)
+	m.records = append(m.records, r)
+}
+
+// Records returns the last successful result of each query.
+func (m *metricClient) Records() []metricRecord {
+	m.Lock()
+	defer m.Unlock()
+	return append([]metricRecord(nil), m.records...)
+}
+
+// Warnings returns the count of warnings of all queries.
+func (m *metricClient) Warnings() int {
+	m.Lock()
+	defer m.Unlock()
+	return m.warnings
+}
+
+// Query evaluates an instant query at t.
+func (m *metricClient) Query(query string, t time.Time) (model.Value, error) {
+	result, warnings, err := m.do(query, func(ctx context.Context) (model.Value, v1.Warnings, error) {
+		return m.api.Query(ctx, query, t)
+	})
+	if err == nil {
+		m.record(metricRecord{Query: query, Time: &t, Result: result, Warnings: warnings})
+	}
+	return result, err
+}
+
+// QueryRange evaluates a range query.
+func (m *metricClient) QueryRange(query string, r v1.Range) (model.Value, error) {
+	result, warnings, err := m.do(query, func(ctx context.Context) (model.Value, v1.Warnings, error) {
+		return m.api.QueryRange(ctx, query, r)
+	})
+	if err == nil {
+		m.record(metricRecord{Query: query, Range: &r, Result: result, Warnings: warnings})
+	}
+	return result, err
+}
@@ -0,0 +1,420 @@
+package bench
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+const (
+	pdAPIPrefix       = "pd/api/v1"
+	pdVersionPath     = "version"
+	pdStoresPath      = "stores"
+	pdStorePath       = "store"
+	pdRegionStatsPath = "stats/region"
+	pdOperatorsPath   = "operators"
+	pdSchedulersPath  = "schedulers"
+	pdConfigPath      = "config"
+	pdMembersPath     = "members"
+	pdLeaderPath      = "leader"
+	pdRegionsPath     = "regions"
+	pdRulePath        = "config/rule"
+)
+
+// StoreMeta is the meta of a store returned by PD.
+type StoreMeta struct {
+	ID        uint64 `json:"id"`
+	Address   string `json:"address"`
+	Version   string `json:"version"`
+	StateName string `json:"state_name"`
+	// Labels are the labels of the store, such as zone, rack and host.
+	Labels []StoreLabel `json:"labels,omitempty"`
+}
+
+// StoreLabel is a label of a store.
+type StoreLabel struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ByteSize is a size which PD shows in binary units, such as "1.9TiB".
+type ByteSize uint64
+
+// UnmarshalJSON implements json.Unmarshaler, a number is the size in bytes.
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		return json.Unmarshal(data, (*uint64)(b))
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	size, err := utils.ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = ByteSize(size)
+	return nil
+}
+
+func (m *StoreMeta) labels() map[string]string {
+	if len(m.Labels) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(m.Labels))
+	for _, l := range m.Labels {
+		labels[l.Key] = l.Value
+	}
+	return labels
+}
+
+// StoreStatus is the status of a store returned by PD.
+type StoreStatus struct {
+	LeaderCount int     `json:"leader_count"`
+	LeaderScore float64 `json:"leader_score"`
+	RegionCount int     `json:"region_count"`
+	RegionScore float64 `json:"region_score"`
+	// RegionSize is in MiB.
+	RegionSize   int64    `json:"region_size"`
+	RegionWeight float64  `json:"region_weight"`
+	Capacity     ByteSize `json:"capacity"`
+	Available    ByteSize `json:"available"`
+	// StartTS is the start time of the store process.
+	StartTS time.Time `json:"start_ts"`
+	// LastHeartbeatTS is the time of the last store heartbeat received by the PD leader.
+	LastHeartbeatTS time.Time `json:"last_heartbeat_ts"`
+}
+
+// StoreInfo is a store returned by PD.
+type StoreInfo struct {
+	Store  StoreMeta   `json:"store"`
+	Status StoreStatus `json:"status"`
+}
+
+// StoresInfo is the response of the stores API.
+type StoresInfo struct {
+	Count  int          `json:"count"`
+	Stores []*StoreInfo `json:"stores"`
+}
+
+// RegionInfo is a region returned by PD, keys are encoded in hex.
+type RegionInfo struct {
+	ID       uint64 `json:"id"`
+	StartKey string `json:"start_key"`
+	EndKey   string `json:"end_key"`
+	Peers    []struct {
+		StoreID uint64 `json:"store_id"`
+	} `json:"peers"`
+	Leader struct {
+		StoreID uint64 `json:"store_id"`
+	} `json:"leader"`
+}
+
+// RegionsInfo is the response of the regions API.
+type RegionsInfo struct {
+	Count   int           `json:"count"`
+	Regions []*RegionInfo `json:"regions"`
+}
+
+// LabelConstraint is a constraint of a placement rule on store labels.
+type LabelConstraint struct {
+	Key    string   `json:"key"`
+	Op     string   `json:"op"`
+	Values []string `json:"values"`
+}
+
+// PlacementRule is a placement rule of PD, keys are encoded in hex.
+type PlacementRule struct {
+	GroupID          string            `json:"group_id"`
+	ID               string            `json:"id"`
+	Index            int               `json:"index,omitempty"`
+	Override         bool              `json:"override,omitempty"`
+	StartKeyHex      string            `json:"start_key"`
+	EndKeyHex        string            `json:"end_key"`
+	Role             string            `json:"role"`
+	Count            int               `json:"count"`
+	LabelConstraints []LabelConstraint `json:"label_constraints,omitempty"`
+	LocationLabels   []string          `json:"location_labels,omitempty"`
+	IsolationLevel   string            `json:"isolation_level,omitempty"`
+}
+
+// RegionStats is the response of the region stats API.
+type RegionStats struct {
+	Count       int   `json:"count"`
+	EmptyCount  int   `json:"empty_count"`
+	StorageSize int64 `json:"storage_size"`
+	StorageKeys int64 `json:"storage_keys"`
+}
+
+// PDMember is a PD member.
+type PDMember struct {
+	Name       string   `json:"name"`
+	MemberID   uint64   `json:"member_id"`
+	ClientUrls []string `json:"client_urls"`
+}
+
+// PDMembers is the response of the members API.
+type PDMembers struct {
+	Members []*PDMember `json:"members"`
+	Leader  *PDMember   `json:"leader"`
+}
+
+func (c *cluster) pdURL(path string) string {
+	addr := c.pdAddr
+	if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") {
+		addr = "http://" + addr
+	}
+	return strings.TrimRight(addr, "/") + "/" + pdAPIPrefix + "/" + path
+}
+
+func (c *cluster) pdGet(path string, v interface{}) error {
+	resp, err := doRequest(c.pdURL(path), http.MethodGet)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(resp), v)
+}
+
+func (c *cluster) getPDVersion() (string, error) {
+	var version struct {
+		Version string `json:"version"`
+	}
+	if err := c.pdGet(pdVersionPath, &version); err != nil {
+		return "", err
+	}
+	return version.Version, nil
+}
+
+func (c *cluster) getStores() ([]*StoreInfo, error) {
+	var stores StoresInfo
+	if err := c.pdGet(pdStoresPath, &stores); err != nil {
+		return nil, err
+	}
+	return stores.Stores, nil
+}
+
+func (c *cluster) getStore(id uint64) (*StoreInfo, error) {
+	store := &StoreInfo{}
+	if err := c.pdGet(pdStorePath+"/"+strconv.FormatUint(id, 10), store); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// getStoreStats returns the stats of stores which are used by report.
+func (c *cluster) getStoreStats() ([]utils.StoreStats, error) {
+	stores, err := c.getStores()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]utils.StoreStats, 0, len(stores))
+	for _, s := range stores {
+		ret = append(ret, utils.StoreStats{
+			ID:           s.Store.ID,
+			Address:      s.Store.Address,
+			State:        s.Store.StateName,
+			LeaderCount:  s.Status.LeaderCount,
+			RegionCount:  s.Status.RegionCount,
+			LeaderScore:  s.Status.LeaderScore,
+			RegionScore:  s.Status.RegionScore,
+			Capacity:     uint64(s.Status.Capacity),
+			Available:    uint64(s.Status.Available),
+			RegionSize:   s.Status.RegionSize,
+			RegionWeight: s.Status.RegionWeight,
+			Labels:       s.Store.labels(),
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ID < ret[j].ID })
+	return ret, nil
+}
+
+// getPlacementRegions returns all regions with the stores of their peers.
+func (c *cluster) getPlacementRegions() ([]utils.PlacementRegion, error) {
+	var regions RegionsInfo
+	if err := c.pdGet(pdRegionsPath, &regions); err != nil {
+		return nil, err
+	}
+	ret := make([]utils.PlacementRegion, 0, len(regions.Regions))
+	for _, r := range regions.Regions {
+		region := utils.PlacementRegion{ID: r.ID, Leader: r.Leader.StoreID}
+		var err error
+		if region.StartKey, err = hex.DecodeString(r.StartKey); err != nil {
+			return nil, errors.Annotatef(err, "invalid start key of region %d", r.ID)
+		}
+		if region.EndKey, err = hex.DecodeString(r.EndKey); err != nil {
+			return nil, errors.Annotatef(err, "invalid end key of region %d", r.ID)
+		}
+		for _, peer := range r.Peers {
+			region.Stores = append(region.Stores, peer.StoreID)
+		}
+		ret = append(ret, region)
+	}
+	return ret, nil
+}
+
+func (c *cluster) getPlacementRule(group, id string) (*PlacementRule, error) {
+	rule := &PlacementRule{}
+	if err := c.pdGet(pdRulePath+"/"+group+"/"+id, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// getReplicationConfig returns a replication config of PD as a string, it is empty if PD does not have it.
+func (c *cluster) getReplicationConfig(key string) (string, error) {
+	config, err := c.getConfig()
+	if err != nil {
+		return "", err
+	}
+	if replication, ok := config["replication"].(map[string]interface{}); ok {
+		config = replication
+	}
+	if v, ok := config[key]; ok {
+		return fmt.Sprint(v), nil
+	}
+	return "", nil
+}
+
+// getPDConfigValue returns a config of PD by its pd-ctl key, which is in a section such as schedule.
+func (c *cluster) getPDConfigValue(key string) (value interface{}, ok bool, err error) {
+	config, err := c.getConfig()
+	if err != nil {
+		return nil, false, err
+	}
+	if v, ok := config[key]; ok {
+		return v, true, nil
+	}
+	for _, section := range config {
+		if values, isMap := section.(map[string]interface{}); isMap {
+			if v, ok := values[key]; ok {
+				return v, true, nil
+			}
+		}
+	}
+	return nil, false, nil
+}
+
+func (c *cluster) getRegionStats() (*RegionStats, error) {
+	stats := &RegionStats{}
+	if err := c.pdGet(pdRegionStatsPath, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// getOperators returns the operators which are not finished, the content is left raw.
+func (c *cluster) getOperators() ([]json.RawMessage, error) {
+	var operators []json.RawMessage
+	if err := c.pdGet(pdOperatorsPath, &operators); err != nil {
+		return nil, err
+	}
+	return operators, nil
+}
+
+func (c *cluster) getSchedulers() ([]string, error) {
+	var schedulers []string
+	if err := c.pdGet(pdSchedulersPath, &schedulers); err != nil {
+		return nil, err
+	}
+	sort.Strings(schedulers)
+	return schedulers, nil
+}
+
+func (c *cluster) getConfig() (map[string]interface{}, error) {
+	config := make(map[string]interface{})
+	if err := c.pdGet(pdConfigPath, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// getBalanceOperators returns the pending operators of balance-leader and balance-region schedulers.
+func (c *cluster) getBalanceOperators() ([]json.RawMessage, error) {
+	operators, err := c.getOperators()
+	if err != nil {
+		return nil, err
+	}
+	var ret []json.RawMessage
+	for _, op := range operators {
+		if utils.IsBalanceOperator(op) {
+			ret = append(ret, op)
+		}
+	}
+	return ret, nil
+}
+
+// isPDBalanced checks balance by scores of stores from PD, and there should be no pending balance operator.
+// Other operators such as hot region and merge ones keep coming on a busy cluster, so that they are not waited for.
+func (c *cluster) isPDBalanced(tolerance float64) (bool, error) {
+	operators, err := c.getBalanceOperators()
+	if err != nil {
+		return false, err
+	}
+	if len(operators) > 0 {
+		log.Debug("balance operators are pending", zap.Int("count", len(operators)))
+		return false, nil
+	}
+	stores, err := c.getStoreStats()
+	if err != nil {
+		return false, err
+	}
+	if !utils.IsStoreBalanced(stores, tolerance) {
+		log.Debug("stores are not balanced", zap.Any("stores", stores))
+		return false, nil
+	}
+	return true, nil
+}
+
+// pdConfigItems are the PD configs which affect scheduling, they are recorded in metadata.
+var pdConfigItems = map[string][]string{
+	"schedule":    {"leader-schedule-limit", "region-schedule-limit", "replica-schedule-limit", "max-pending-peer-count", "max-snapshot-count"},
+	"replication": {"max-replicas"},
+}
+
+// recordPDConfig records schedulers and scheduling configs of PD in metadata.
+func (c *cluster) recordPDConfig() error {
+	schedulers, err := c.getSchedulers()
+	if err != nil {
+		return err
+	}
+	c.SetConfig("pd.schedulers", strings.Join(schedulers, ","))
+	config, err := c.getConfig()
+	if err != nil {
+		return err
+	}
+	for section, items := range pdConfigItems {
+		values, _ := config[section].(map[string]interface{})
+		for _, item := range items {
+			if v, ok := values[item]; ok {
+				c.SetConfig("pd."+item, fmt.Sprint(v))
+			}
+		}
+	}
+	return nil
+}
+
+func (c *cluster) getPDMembers() (*PDMembers, error) {
+	members := &PDMembers{}
+	if err := c.pdGet(pdMembersPath, members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (c *cluster) getPDLeader() (*PDMember, error) {
+	leader := &PDMember{}
+	if err := c.pdGet(pdLeaderPath, leader); err != nil {
+		return nil, err
+	}
+	return leader, nil
+}
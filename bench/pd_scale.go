@@ -0,0 +1,170 @@
+package bench
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+func createPDScaleOutCase(cluster *cluster) *benchCase {
+	return &benchCase{
+		generator: newEmptyGenerator(),
+		bench:     newPDScaleOut(cluster),
+	}
+}
+
+// pdScaleOut adds PD members while a workload is running, and observes
+// whether the membership changes the leader or interrupts TSO.
+type pdScaleOut struct {
+	c   *cluster
+	num int
+	// added is the addresses of the members which are added since the last reset
+	added   []string
+	results []*utils.PDScaleOutOnce
+}
+
+func newPDScaleOut(c *cluster) bench {
+	num, err := strconv.Atoi(os.Getenv("SCALE_NUM"))
+	if err != nil {
+		num = 1 // default
+	}
+	return &pdScaleOut{c: c, num: num}
+}
+
+func (s *pdScaleOut) Run() error {
+	s.c.SetConfig("SCALE_NUM", strconv.Itoa(s.num))
+	p, err := s.c.startProbe()
+	if err != nil {
+		return err
+	}
+	defer p.Stop()
+	warmupStart := time.Now()
+	time.Sleep(switchWarmup)
+
+	w, err := s.c.watchPDLeader()
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+	scaleTime := time.Now()
+	added, err := s.c.ScaleOut(componentPD, s.num)
+	if err != nil {
+		return err
+	}
+	s.added = append(s.added, added...)
+	rep := &utils.PDScaleOutOnce{ReadyTime: time.Since(scaleTime).Seconds()}
+
+	time.Sleep(switchObserve)
+	w.Stop()
+	p.Stop()
+	observeEnd := time.Now()
+
+	members, err := s.c.getPDMembers()
+	if err != nil {
+		return err
+	}
+	rep.Members = len(members.Members)
+	rep.LeaderChanges = w.changes
+	rep.PrevP99Latency = latencyQuantile(p.window(warmupStart, scaleTime), 0.99)
+	ops := p.window(scaleTime, observeEnd)
+	rep.ScaleP99Latency = latencyQuantile(ops, 0.99)
+	rep.FailedQueries = failedCount(ops)
+	rep.TsoUnavailable = longestGap(p.window(warmupStart, observeEnd), scaleTime, observeEnd)
+	log.Info("pd members are scaled out", zap.Any("stats", rep))
+	s.results = append(s.results, rep)
+	return nil
+}
+
+// Reset removes the added members, so that the next iteration starts from the same membership.
+func (s *pdScaleOut) Reset() error {
+	added := s.added
+	s.added = nil
+	if len(added) == 0 {
+		return nil
+	}
+	return s.c.ScaleIn(componentPD, added)
+}
+
+func (s *pdScaleOut) Collect() error {
+	if len(s.results) == 0 {
+		return errors.New("no result to report")
+	}
+	agg, err := utils.Aggregate(s.results)
+	if err != nil {
+		return err
+	}
+	rep := agg.(*utils.PDScaleOutOnce)
+	rep.Meta = s.c.runMetadata()
+	return s.c.collectReport(rep, rep.Meta)
+}
+
+// sweepResult implements sweeper.
+func (s *pdScaleOut) sweepResult() (interface{}, error) {
+	if len(s.results) == 0 {
+		return nil, errors.New("no result to report")
+	}
+	rep, err := utils.Aggregate(s.results)
+	if err != nil {
+		return nil, err
+	}
+	s.results = nil
+	return rep, nil
+}
+
+// leaderWatcher samples the PD leader and counts its changes.
+type leaderWatcher struct {
+	c       *cluster
+	leader  string
+	changes int
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// watchPDLeader starts to sample the PD leader from the current one.
+func (c *cluster) watchPDLeader() (*leaderWatcher, error) {
+	leader, err := c.getPDLeader()
+	if err != nil {
+		return nil, err
+	}
+	w := &leaderWatcher{c: c, leader: leader.Name, stop: make(chan struct{})}
+	w.wg.Add(1)
+	go w.run()
+	return w, nil
+}
+
+func (w *leaderWatcher) run() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+		leader, err := w.c.getPDLeader()
+		if err != nil || leader.Name == "" {
+			continue
+		}
+		if leader.Name != w.leader {
+			log.Info("pd leader changes", zap.String("from", w.leader), zap.String("to", leader.Name))
+			w.leader = leader.Name
+			w.changes++
+		}
+	}
+}
+
+// Stop stops sampling, changes are stable after it.
+func (w *leaderWatcher) Stop() {
+	select {
+	case <-w.stop:
+		return
+	default:
+		close(w.stop)
+	}
+	w.wg.Wait()
+}
@@ -0,0 +1,225 @@
+package bench
+
+import (
+	"os"
+	"time"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/prometheus/common/model"
+	"go.uber.org/zap"
+)
+
+const (
+	switchModeTransfer = "transfer"
+	switchModeResign   = "resign"
+
+	// switchWarmup is the time before the switch, the latency in it is the baseline.
+	switchWarmup = 30 * time.Second
+	// switchObserve is the time after the switch in which the spike is measured.
+	switchObserve = time.Minute
+)
+
+func createPDLeaderSwitchCase(cluster *cluster) *benchCase {
+	return &benchCase{
+		generator: newEmptyGenerator(),
+		bench:     newPDLeaderSwitch(cluster),
+	}
+}
+
+// pdLeaderSwitch switches the PD leader while a workload is running.
+type pdLeaderSwitch struct {
+	c       *cluster
+	mode    string
+	results []*utils.PDLeaderSwitchOnce
+}
+
+func newPDLeaderSwitch(c *cluster) bench {
+	mode := os.Getenv("PD_SWITCH_MODE")
+	if mode == "" {
+		mode = switchModeTransfer
+	}
+	return &pdLeaderSwitch{c: c, mode: mode}
+}
+
+func (s *pdLeaderSwitch) Run() error {
+	s.c.SetConfig("PD_SWITCH_MODE", s.mode)
+	members, err := s.c.getPDMembers()
+	if err != nil {
+		return err
+	}
+	if len(members.Members) < 2 || members.Leader == nil {
+		return errors.Errorf("pd leader switch needs at least two pd members, but there are %d", len(members.Members))
+	}
+	oldLeader := members.Leader.Name
+
+	p, err := s.c.startProbe()
+	if err != nil {
+		return err
+	}
+	defer p.Stop()
+	warmupStart := time.Now()
+	time.Sleep(switchWarmup)
+
+	switchTime := time.Now()
+	if err := s.switchLeader(members); err != nil {
+		return err
+	}
+	newLeader, err := s.c.waitPDLeaderChange(oldLeader)
+	if err != nil {
+		return err
+	}
+	log.Info("pd leader is switched", zap.String("from", oldLeader), zap.String("to", newLeader))
+	rep := &utils.PDLeaderSwitchOnce{ElectionTime: time.Since(switchTime).Seconds()}
+	if err := s.c.waitStoreHeartbeats(switchTime); err != nil {
+		return err
+	}
+	rep.StoreHeartbeatResume = time.Since(switchTime).Seconds()
+
+	time.Sleep(switchObserve - time.Since(switchTime))
+	p.Stop()
+	observeEnd := time.Now()
+
+	rep.PrevP99Latency = latencyQuantile(p.window(warmupStart, switchTime), 0.99)
+	ops := p.window(switchTime, observeEnd)
+	rep.SwitchP99Latency = latencyQuantile(ops, 0.99)
+	rep.SwitchMaxLatency = latencyQuantile(ops, 1)
+	rep.FailedQueries = failedCount(ops)
+	rep.TsoUnavailable = longestGap(p.window(warmupStart, observeEnd), switchTime, observeEnd)
+
+	resume, err := s.regionHeartbeatResume(switchTime, observeEnd)
+	if isNoData(err) {
+		rep.Missing = append(rep.Missing, "RegionHeartbeatResume")
+	} else if err != nil {
+		return err
+	}
+	rep.RegionHeartbeatResume = resume
+	s.results = append(s.results, rep)
+	return nil
+}
+
+// switchLeader transfers the leader to another member, or makes it resign.
+func (s *pdLeaderSwitch) switchLeader(members *PDMembers) error {
+	switch s.mode {
+	case switchModeResign:
+		return s.c.ResignPDLeader()
+	case switchModeTransfer:
+		for _, m := range members.Members {
+			if m.Name != members.Leader.Name {
+				return s.c.TransferPDLeader(m.Name)
+			}
+		}
+		return errors.New("no pd member to transfer leader")
+	default:
+		return errors.Errorf("unknown PD_SWITCH_MODE %s, support list: %s,%s", s.mode, switchModeTransfer, switchModeResign)
+	}
+}
+
+// regionHeartbeatResume returns the seconds from the switch until the handled region heartbeats increase.
+// It is measured by the timestamps of scrapes, so that it is not finer than the scrape interval.
+func (s *pdLeaderSwitch) regionHeartbeatResume(from, to time.Time) (float64, error) {
+	// the window starts before the switch, so that the last scrape before it is the baseline
+	matrix, err := s.c.getScrapedMetric("pd_scheduler_region_heartbeat{type=\"report\", status=\"ok\"}", to.Sub(from)+switchWarmup, to)
+	if err != nil {
+		return 0, err
+	}
+	resume := -1.0
+	for _, series := range matrix {
+		// a series which shows up after the switch counts from 0
+		var base model.SampleValue
+		for _, sample := range series.Values {
+			t := sample.Timestamp.Time()
+			if !t.After(from) {
+				base = sample.Value
+				continue
+			}
+			if sample.Value > base {
+				if d := t.Sub(from).Seconds(); resume < 0 || d < resume {
+					resume = d
+				}
+				break
+			}
+		}
+	}
+	if resume < 0 {
+		return 0, errors.Annotate(errNoData, "region heartbeats are not resumed")
+	}
+	return resume, nil
+}
+
+// resumeReport notes the precision of RegionHeartbeatResume.
+func resumeReport() string {
+	return "note:  \n\t* RegionHeartbeatResume is measured by scrapes of prometheus, it is not finer than the scrape interval  \n"
+}
+
+func (s *pdLeaderSwitch) Collect() error {
+	if len(s.results) == 0 {
+		return errors.New("no result to report")
+	}
+	agg, err := utils.Aggregate(s.results)
+	if err != nil {
+		return err
+	}
+	rep := agg.(*utils.PDLeaderSwitchOnce)
+	rep.Meta = s.c.runMetadata()
+	return s.c.collectReport(rep, rep.Meta, resumeReport(), missingReport(rep.Missing))
+}
+
+// sweepResult implements sweeper.
+func (s *pdLeaderSwitch) sweepResult() (interface{}, error) {
+	if len(s.results) == 0 {
+		return nil, errors.New("no result to report")
+	}
+	rep, err := utils.Aggregate(s.results)
+	if err != nil {
+		return nil, err
+	}
+	s.results = nil
+	return rep, nil
+}
+
+// waitPDLeaderChange waits until a member other than old becomes the leader, and returns its name.
+func (c *cluster) waitPDLeaderChange(old string) (string, error) {
+	deadline := time.Now().Add(pdReadyTimeout())
+	for {
+		leader, err := c.getPDLeader()
+		if err == nil && leader.Name != "" && leader.Name != old {
+			return leader.Name, nil
+		}
+		if time.Now().After(deadline) {
+			if err == nil {
+				err = errors.Errorf("pd leader is still %s", leader.Name)
+			}
+			return "", errors.Annotate(err, "pd leader is not switched")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// waitStoreHeartbeats waits until all up stores send heartbeats after since.
+func (c *cluster) waitStoreHeartbeats(since time.Time) error {
+	deadline := time.Now().Add(pdReadyTimeout())
+	for {
+		stores, err := c.getStores()
+		if err == nil {
+			resumed := true
+			for _, s := range stores {
+				if s.Store.StateName == utils.StoreUp && s.Status.LastHeartbeatTS.Before(since) {
+					resumed = false
+					break
+				}
+			}
+			if resumed {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			if err == nil {
+				err = errors.New("some stores do not send heartbeats")
+			}
+			return errors.Annotate(err, "store heartbeats are not resumed")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
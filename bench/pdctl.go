@@ -0,0 +1,255 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPDReadyTimeout       = time.Minute
+	defaultStoreRegisterTimeout = 30 * time.Minute
+	pdRetryInterval             = time.Second
+)
+
+// pdReadyTimeout is how long PD control operations are retried, it is set by PD_READY_TIMEOUT in seconds.
+func pdReadyTimeout() time.Duration {
+	if sec, err := strconv.Atoi(os.Getenv("PD_READY_TIMEOUT")); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return defaultPDReadyTimeout
+}
+
+// storeRegisterTimeout is how long new stores are waited for to be deployed, and then to be up in PD,
+// it is set by STORE_REGISTER_TIMEOUT in seconds.
+func storeRegisterTimeout() time.Duration {
+	if sec, err := strconv.Atoi(os.Getenv("STORE_REGISTER_TIMEOUT")); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return defaultStoreRegisterTimeout
+}
+
+// isPDRetryable returns false if PD rejects the request, retrying a bad request is useless.
+func isPDRetryable(err error) bool {
+	if e, ok := errors.Cause(err).(*statusError); ok {
+		return e.code >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// pdDo sends a request to PD, it is retried until PD is ready or the timeout is reached.
+func (c *cluster) pdDo(method, path string, input interface{}) error {
+	var data []byte
+	if input != nil {
+		var err error
+		if data, err = json.Marshal(input); err != nil {
+			return err
+		}
+	}
+	deadline := time.Now().Add(pdReadyTimeout())
+	for {
+		var opts []BodyOption
+		if data != nil {
+			opts = append(opts, WithBody("application/json", bytes.NewReader(data)))
+		}
+		_, err := doRequest(c.pdURL(path), method, opts...)
+		if err == nil {
+			return nil
+		}
+		if !isPDRetryable(err) || time.Now().After(deadline) {
+			return errors.Annotatef(err, "%s %s", method, path)
+		}
+		log.Warn("pd is not ready, retry", zap.String("method", method), zap.String("path", path), zap.Error(err))
+		time.Sleep(pdRetryInterval)
+	}
+}
+
+// waitUntil checks cond every interval until it is true, what describes cond in the error of timeout.
+func waitUntil(what string, timeout, interval time.Duration, cond func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := cond()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.Errorf("timeout after %s when waiting for %s", timeout, what)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// WaitPDReady waits until PD serves and there are at least minStores up stores.
+func (c *cluster) WaitPDReady(minStores int) error {
+	deadline := time.Now().Add(pdReadyTimeout())
+	for {
+		stores, err := c.getStoreStats()
+		up := 0
+		for _, s := range stores {
+			if s.State == utils.StoreUp {
+				up++
+			}
+		}
+		if err == nil && up >= minStores {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err == nil {
+				err = errors.Errorf("%d stores are up, want %d", up, minStores)
+			}
+			return errors.Annotate(err, "pd is not ready")
+		}
+		log.Debug("wait for pd", zap.Int("up", up), zap.Error(err))
+		time.Sleep(pdRetryInterval)
+	}
+}
+
+// getUpStoreNum returns the number of up stores in PD.
+func (c *cluster) getUpStoreNum() (int, error) {
+	stores, err := c.getStoreStats()
+	if err != nil {
+		return 0, err
+	}
+	up := 0
+	for _, store := range stores {
+		if store.State == utils.StoreUp {
+			up++
+		}
+	}
+	return up, nil
+}
+
+// waitNewStores waits until there are num up stores which are not in before, and returns the first num of them.
+// Other stores may show up at the same time, so that more than num stores are accepted.
+func (c *cluster) waitNewStores(before []utils.StoreStats, num int, timeout time.Duration) ([]utils.StoreStats, error) {
+	known := make(map[uint64]struct{}, len(before))
+	for _, store := range before {
+		known[store.ID] = struct{}{}
+	}
+	var added []utils.StoreStats
+	err := waitUntil("new stores to be up", timeout, time.Second, func() (bool, error) {
+		stores, err := c.getStoreStats()
+		if err != nil {
+			return false, err
+		}
+		added = added[:0]
+		for _, store := range stores {
+			if _, ok := known[store.ID]; !ok && store.State == utils.StoreUp {
+				added = append(added, store)
+			}
+		}
+		return len(added) >= num, nil
+	})
+	if len(added) > num {
+		added = added[:num]
+	}
+	return added, err
+}
+
+// SetStoreLimit sets the store limit of a store, as pd-ctl store limit <id> <rate>.
+func (c *cluster) SetStoreLimit(storeID uint64, rate float64) error {
+	return c.pdDo(http.MethodPost, pdStorePath+"/"+strconv.FormatUint(storeID, 10)+"/limit", map[string]interface{}{"rate": rate})
+}
+
+// SetAllStoreLimit sets the store limit of all stores, as pd-ctl store limit all <rate>.
+func (c *cluster) SetAllStoreLimit(rate float64) error {
+	return c.pdDo(http.MethodPost, pdStoresPath+"/limit", map[string]interface{}{"rate": rate})
+}
+
+// DeleteStore makes the store offline, its regions are moved away until it is tombstone, as pd-ctl store delete <id>.
+func (c *cluster) DeleteStore(storeID uint64) error {
+	return c.pdDo(http.MethodDelete, pdStorePath+"/"+strconv.FormatUint(storeID, 10), nil)
+}
+
+// RemoveTombstone removes all tombstone stores, as pd-ctl store remove-tombstone.
+func (c *cluster) RemoveTombstone() error {
+	return c.pdDo(http.MethodDelete, pdStoresPath+"/remove-tombstone", nil)
+}
+
+// SetStoreWeight sets the leader and region weight of a store, as pd-ctl store weight <id> <leader> <region>.
+func (c *cluster) SetStoreWeight(storeID uint64, leader, region float64) error {
+	return c.pdDo(http.MethodPost, pdStorePath+"/"+strconv.FormatUint(storeID, 10)+"/weight",
+		map[string]interface{}{"leader": leader, "region": region})
+}
+
+// SetStoreLabels sets labels of a store, as pd-ctl store label <id> <key> <value>.
+func (c *cluster) SetStoreLabels(storeID uint64, labels map[string]string) error {
+	return c.pdDo(http.MethodPost, pdStorePath+"/"+strconv.FormatUint(storeID, 10)+"/label", labels)
+}
+
+// labelStores sets labels to the stores, which are selected by the labels of their machines.
+func (c *cluster) labelStores(stores []utils.StoreStats, labels map[string]string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	for _, store := range stores {
+		if err := c.SetStoreLabels(store.ID, labels); err != nil {
+			return errors.Annotatef(err, "failed to set labels of store %d", store.ID)
+		}
+	}
+	return nil
+}
+
+// SetPlacementRule adds or updates a placement rule, as pd-ctl config placement-rules save.
+func (c *cluster) SetPlacementRule(rule *PlacementRule) error {
+	return c.pdDo(http.MethodPost, pdRulePath, rule)
+}
+
+// DeletePlacementRule deletes a placement rule.
+func (c *cluster) DeletePlacementRule(group, id string) error {
+	return c.pdDo(http.MethodDelete, pdRulePath+"/"+group+"/"+id, nil)
+}
+
+// AddScheduler adds a scheduler, args are the arguments of the scheduler such as store_id.
+func (c *cluster) AddScheduler(name string, args map[string]interface{}) error {
+	input := map[string]interface{}{"name": name}
+	for k, v := range args {
+		input[k] = v
+	}
+	return c.pdDo(http.MethodPost, pdSchedulersPath, input)
+}
+
+// RemoveScheduler removes a scheduler.
+func (c *cluster) RemoveScheduler(name string) error {
+	return c.pdDo(http.MethodDelete, pdSchedulersPath+"/"+name, nil)
+}
+
+// PauseScheduler pauses a scheduler for delay, a zero delay resumes it.
+func (c *cluster) PauseScheduler(name string, delay time.Duration) error {
+	return c.pdDo(http.MethodPost, pdSchedulersPath+"/"+name, map[string]interface{}{"delay": int64(delay.Seconds())})
+}
+
+// SetPDConfig sets a config of PD, as pd-ctl config set <key> <value>.
+func (c *cluster) SetPDConfig(key string, value interface{}) error {
+	return c.pdDo(http.MethodPost, pdConfigPath, map[string]interface{}{key: value})
+}
+
+// AddOperator adds an operator, args are the arguments of the operator such as region_id and to_store_id.
+func (c *cluster) AddOperator(name string, args map[string]interface{}) error {
+	input := map[string]interface{}{"name": name}
+	for k, v := range args {
+		input[k] = v
+	}
+	return c.pdDo(http.MethodPost, pdOperatorsPath, input)
+}
+
+// ResignPDLeader makes the PD leader resign, then another member is elected.
+func (c *cluster) ResignPDLeader() error {
+	return c.pdDo(http.MethodPost, pdLeaderPath+"/resign", nil)
+}
+
+// TransferPDLeader transfers the PD leader to the member.
+func (c *cluster) TransferPDLeader(name string) error {
+	return c.pdDo(http.MethodPost, pdLeaderPath+"/transfer/"+name, nil)
+}
@@ -0,0 +1,403 @@
+package bench
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/siddontang/go-mysql/client"
+	"go.uber.org/zap"
+)
+
+const (
+	placementZoneIsolation = "zone-isolation"
+	placementLeaderPin     = "leader-pin"
+
+	zoneLabel = "zone"
+	// placementTimeout is the timeout of placement to be compliant.
+	placementTimeout = 30 * time.Minute
+	// placementInterval is the interval of counting violations.
+	placementInterval = 5 * time.Second
+	defaultRuleGroup  = "pd"
+	leaderPinRule     = "bench-leader-pin"
+	followerPinRule   = "bench-follower-pin"
+)
+
+func createPlacementCase(cluster *cluster) *benchCase {
+	return &benchCase{
+		generator: newYCSB(cluster, "workload-scale-out"),
+		bench:     newPlacement(cluster),
+	}
+}
+
+// placement configures placement rules, scales out stores in a zone, and counts
+// the regions which violate the rules until PD makes them compliant.
+type placement struct {
+	c    *cluster
+	num  int
+	mode string
+	zone string
+	// zones are assigned to the existing stores which have no zone label
+	zones []string
+	table string
+	// start and end is the range of the table whose leaders are pinned
+	start, end []byte
+	// saved is the config of PD before setup, it is nil if there is nothing to clean up
+	saved *placementConfig
+	// added is the stores which are added by the last run
+	added   []utils.StoreStats
+	results []*utils.PlacementOnce
+}
+
+// placementConfig is the config of PD which is changed by setup.
+type placementConfig struct {
+	locationLabels string
+	enabled        bool
+	defaultRule    *PlacementRule
+}
+
+func newPlacement(c *cluster) bench {
+	num, err := strconv.Atoi(os.Getenv("SCALE_NUM"))
+	if err != nil {
+		num = 1 // default
+	}
+	p := &placement{
+		c:     c,
+		num:   num,
+		mode:  os.Getenv("PLACEMENT_MODE"),
+		zone:  os.Getenv("PLACEMENT_ZONE"),
+		zones: strings.Split(os.Getenv("PLACEMENT_ZONES"), ","),
+		table: os.Getenv("PLACEMENT_TABLE"),
+	}
+	if os.Getenv("PLACEMENT_ZONES") == "" {
+		p.zones = []string{"z1", "z2", "z3"}
+	}
+	if p.mode == "" {
+		p.mode = placementZoneIsolation
+	}
+	if p.zone == "" {
+		p.zone = "z1"
+	}
+	if p.table == "" {
+		p.table = "test.test_go_ycsb"
+	}
+	return p
+}
+
+func (p *placement) Run() error {
+	p.c.SetConfig("PLACEMENT_MODE", p.mode)
+	p.c.SetConfig("PLACEMENT_ZONE", p.zone)
+	p.c.SetConfig("PLACEMENT_ZONES", strings.Join(p.zones, ","))
+	p.c.SetConfig("SCALE_NUM", strconv.Itoa(p.num))
+	defer func() {
+		if err := p.cleanup(); err != nil {
+			log.Warn("failed to clean up placement rules", zap.Error(err))
+		}
+	}()
+	if err := p.setup(); err != nil {
+		return errors.Annotate(err, "failed to set up placement rules")
+	}
+	// the cluster should be compliant before the scale out
+	err := waitUntil("placement to be compliant", placementTimeout, placementInterval, func() (bool, error) {
+		violations, err := p.violations()
+		return violations == 0, err
+	})
+	if err != nil {
+		return err
+	}
+
+	before, err := p.c.getStoreStats()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	selector := &ResourceSelector{Labels: map[string]string{zoneLabel: p.zone}}
+	for i := 0; i < p.num; i++ {
+		if err := p.c.AddStoreOf(selector); err != nil {
+			return err
+		}
+	}
+	if p.added, err = p.c.waitNewStores(before, p.num, placementTimeout); err != nil {
+		return err
+	}
+	if err := p.c.labelStores(p.added, selector.Labels); err != nil {
+		return err
+	}
+	rep := &utils.PlacementOnce{}
+	err = waitUntil("placement to be compliant after scale out", placementTimeout, placementInterval, func() (bool, error) {
+		violations, err := p.violations()
+		if err != nil {
+			return false, err
+		}
+		rep.Violations = append(rep.Violations, utils.ViolationSample{Seconds: time.Since(start).Seconds(), Count: violations})
+		if violations > rep.MaxViolations {
+			rep.MaxViolations = violations
+		}
+		if violations > 0 {
+			return false, nil
+		}
+		// scores are not compared, stores in the scaled zone cannot reach the scores
+		// of other zones as each zone holds one replica
+		operators, err := p.c.getOperators()
+		return len(operators) == 0, err
+	})
+	if err != nil {
+		return err
+	}
+	rep.ComplianceTime = time.Since(start).Seconds()
+	if rep.FinalViolations, err = p.violations(); err != nil {
+		return err
+	}
+	rep.Operators, err = p.c.finishedOperators(start)
+	if isNoData(err) {
+		rep.Missing = append(rep.Missing, "Operators")
+	} else if err != nil {
+		return err
+	}
+	log.Info("placement is compliant", zap.Float64("compliance time", rep.ComplianceTime), zap.Int("max violations", rep.MaxViolations))
+	p.results = append(p.results, rep)
+	return nil
+}
+
+// setup enables placement rules and saves the rules of the mode, the config before it is kept for cleanup.
+func (p *placement) setup() error {
+	if err := p.labelZones(); err != nil {
+		return err
+	}
+	saved := &placementConfig{}
+	var err error
+	if saved.locationLabels, err = p.c.getReplicationConfig("location-labels"); err != nil {
+		return err
+	}
+	enabled, err := p.c.getReplicationConfig("enable-placement-rules")
+	if err != nil {
+		return err
+	}
+	saved.enabled = enabled == "true"
+	p.saved = saved
+	if err := p.c.SetPDConfig("location-labels", zoneLabel); err != nil {
+		return err
+	}
+	if err := p.c.SetPDConfig("enable-placement-rules", true); err != nil {
+		return err
+	}
+	// the default rule exists once placement rules are enabled
+	if saved.defaultRule, err = p.c.getPlacementRule(defaultRuleGroup, "default"); err != nil {
+		return err
+	}
+	switch p.mode {
+	case placementZoneIsolation:
+		// one replica per zone
+		return p.c.SetPlacementRule(&PlacementRule{
+			GroupID:        defaultRuleGroup,
+			ID:             "default",
+			Role:           "voter",
+			Count:          3,
+			LocationLabels: []string{zoneLabel},
+			IsolationLevel: zoneLabel,
+		})
+	case placementLeaderPin:
+		p.c.SetConfig("PLACEMENT_TABLE", p.table)
+		id, err := p.tableID()
+		if err != nil {
+			return err
+		}
+		p.start, p.end = utils.TableKeyRange(id)
+		for _, rule := range LeaderPinRules(p.start, p.end, p.zone) {
+			if err := p.c.SetPlacementRule(rule); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return errors.Errorf("unknown PLACEMENT_MODE %s, support list: %s,%s", p.mode, placementZoneIsolation, placementLeaderPin)
+	}
+}
+
+// labelZones sets zones to the existing stores which have no zone label, so that they can be
+// compliant before the scale out. The labels are kept after cleanup, they are inert without rules.
+func (p *placement) labelZones() error {
+	stores, err := p.c.getStoreStats()
+	if err != nil {
+		return err
+	}
+	zones := utils.AssignZones(stores, zoneLabel, p.zones)
+	for id, zone := range zones {
+		if err := p.c.SetStoreLabels(id, map[string]string{zoneLabel: zone}); err != nil {
+			return errors.Annotatef(err, "failed to set zone of store %d", id)
+		}
+	}
+	if p.mode != placementLeaderPin {
+		return nil
+	}
+	// leaders are pinned before the scale out, so that a store of the zone is needed
+	for _, store := range stores {
+		if store.State == utils.StoreUp && (store.Labels[zoneLabel] == p.zone || zones[store.ID] == p.zone) {
+			return nil
+		}
+	}
+	return errors.Errorf("no store in zone %s to pin leaders before the scale out, set it in PLACEMENT_ZONES", p.zone)
+}
+
+// cleanup undoes setup, so that the rules and config do not leak into later iterations and cases.
+// It goes on after an error, and returns the first one.
+func (p *placement) cleanup() error {
+	saved := p.saved
+	if saved == nil {
+		return nil
+	}
+	p.saved = nil
+	var first error
+	keep := func(err error) {
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	if p.mode == placementLeaderPin {
+		keep(p.c.DeletePlacementRule(defaultRuleGroup, leaderPinRule))
+		keep(p.c.DeletePlacementRule(defaultRuleGroup, followerPinRule))
+	}
+	if saved.defaultRule != nil {
+		keep(p.c.SetPlacementRule(saved.defaultRule))
+	}
+	keep(p.c.SetPDConfig("enable-placement-rules", saved.enabled))
+	keep(p.c.SetPDConfig("location-labels", saved.locationLabels))
+	return first
+}
+
+// Reset cleans up the rules if they are left, and removes the added stores, so that
+// the next iteration starts from the same topology and config.
+func (p *placement) Reset() error {
+	if err := p.cleanup(); err != nil {
+		return err
+	}
+	added := p.added
+	p.added = nil
+	if len(added) == 0 {
+		return nil
+	}
+	return p.c.removeStores(added, placementTimeout)
+}
+
+// LeaderPinRules returns the rules which pin leaders of [start, end) to the zone. They override
+// the default rule in the range, so that followers are placed by a rule of the same index
+// to keep 3 peers.
+func LeaderPinRules(start, end []byte, zone string) []*PlacementRule {
+	startHex, endHex := hex.EncodeToString(start), hex.EncodeToString(end)
+	return []*PlacementRule{
+		{
+			GroupID:          defaultRuleGroup,
+			ID:               leaderPinRule,
+			Index:            1,
+			Override:         true,
+			StartKeyHex:      startHex,
+			EndKeyHex:        endHex,
+			Role:             "leader",
+			Count:            1,
+			LabelConstraints: []LabelConstraint{{Key: zoneLabel, Op: "in", Values: []string{zone}}},
+		},
+		{
+			GroupID:     defaultRuleGroup,
+			ID:          followerPinRule,
+			Index:       1,
+			StartKeyHex: startHex,
+			EndKeyHex:   endHex,
+			Role:        "follower",
+			Count:       2,
+		},
+	}
+}
+
+// tableID queries the id of the table from TiDB.
+func (p *placement) tableID() (int64, error) {
+	parts := strings.SplitN(p.table, ".", 2)
+	if len(parts) != 2 {
+		return 0, errors.Errorf("invalid PLACEMENT_TABLE %s, it should be db.table", p.table)
+	}
+	conn, err := client.Connect(p.c.tidbAddr, "root", "", "")
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	res, err := conn.Execute("SELECT TIDB_TABLE_ID FROM information_schema.tables WHERE table_schema = ? AND table_name = ?", parts[0], parts[1])
+	if err != nil {
+		return 0, err
+	}
+	if res.RowNumber() == 0 {
+		return 0, errors.Errorf("table %s is not found", p.table)
+	}
+	return res.GetInt(0, 0)
+}
+
+// violations counts the regions which violate the rules of the mode.
+func (p *placement) violations() (int, error) {
+	regions, err := p.c.getPlacementRegions()
+	if err != nil {
+		return 0, err
+	}
+	stores, err := p.c.getStoreStats()
+	if err != nil {
+		return 0, err
+	}
+	labels := make(map[uint64]map[string]string, len(stores))
+	for _, store := range stores {
+		labels[store.ID] = store.Labels
+	}
+	if p.mode == placementLeaderPin {
+		return utils.LeaderViolations(regions, labels, zoneLabel, p.zone, p.start, p.end), nil
+	}
+	return utils.IsolationViolations(regions, labels, zoneLabel), nil
+}
+
+// violationReport reports the count of violations over time.
+func violationReport(samples []utils.ViolationSample) string {
+	if len(samples) == 0 {
+		return ""
+	}
+	plainText := "violations:  \n"
+	for _, s := range samples {
+		plainText += fmt.Sprintf(" \t* %.0fs: %d  \n", s.Seconds, s.Count)
+	}
+	return plainText
+}
+
+func (p *placement) aggregate() (*utils.PlacementOnce, error) {
+	agg, err := utils.Aggregate(p.results)
+	if err != nil {
+		return nil, err
+	}
+	rep := agg.(*utils.PlacementOnce)
+	rep.Violations = p.results[len(p.results)-1].Violations
+	return rep, nil
+}
+
+func (p *placement) Collect() error {
+	if len(p.results) == 0 {
+		return errors.New("no result to report")
+	}
+	rep, err := p.aggregate()
+	if err != nil {
+		return err
+	}
+	rep.Meta = p.c.runMetadata()
+	return p.c.collectReport(rep, rep.Meta, violationReport(rep.Violations), missingReport(rep.Missing))
+}
+
+// sweepResult implements sweeper.
+func (p *placement) sweepResult() (interface{}, error) {
+	if len(p.results) == 0 {
+		return nil, errors.New("no result to report")
+	}
+	rep, err := p.aggregate()
+	if err != nil {
+		return nil, err
+	}
+	p.results = nil
+	return rep, nil
+}
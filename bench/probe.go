@@ -0,0 +1,199 @@
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/siddontang/go-mysql/client"
+	"go.uber.org/zap"
+)
+
+const (
+	probeThreads = 4
+	probeKeys    = 1000
+	probeTable   = "bench_probe"
+)
+
+// probeOp is an operation of the probe workload.
+type probeOp struct {
+	start   time.Time
+	latency time.Duration
+	failed  bool
+}
+
+func (op probeOp) end() time.Time {
+	return op.start.Add(op.latency)
+}
+
+// probe is a light write workload on TiDB, every autocommit write gets timestamps from PD,
+// so that the latency shows how long TSO is unavailable.
+type probe struct {
+	c    *cluster
+	stop chan struct{}
+	wg   sync.WaitGroup
+
+	sync.Mutex
+	// ops is the operations since the last trim, windows before it are not asked any more
+	ops []probeOp
+}
+
+// startProbe creates the table of probe and starts the workload.
+func (c *cluster) startProbe() (*probe, error) {
+	conn, err := client.Connect(c.tidbAddr, "root", "", "test")
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Execute(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INT PRIMARY KEY, v BIGINT)", probeTable))
+	conn.Close()
+	if err != nil {
+		return nil, err
+	}
+	p := &probe{c: c, stop: make(chan struct{})}
+	for i := 0; i < probeThreads; i++ {
+		p.wg.Add(1)
+		go p.run(i)
+	}
+	return p, nil
+}
+
+func (p *probe) run(thread int) {
+	defer p.wg.Done()
+	var conn *client.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	for {
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+		start := time.Now()
+		var err error
+		if conn == nil {
+			conn, err = client.Connect(p.c.tidbAddr, "root", "", "test")
+		}
+		if err == nil {
+			id := thread*probeKeys + rand.Intn(probeKeys)
+			_, err = conn.Execute(fmt.Sprintf("INSERT INTO %s VALUES (%d, 1) ON DUPLICATE KEY UPDATE v = v + 1", probeTable, id))
+		}
+		if err != nil {
+			log.Debug("probe failed", zap.Int("thread", thread), zap.Error(err))
+			// the connection may be broken, so that it is created again
+			if conn != nil {
+				conn.Close()
+				conn = nil
+			}
+		}
+		p.Lock()
+		p.ops = append(p.ops, probeOp{start: start, latency: time.Since(start), failed: err != nil})
+		p.Unlock()
+		if err != nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// Stop stops the workload and waits for running operations.
+func (p *probe) Stop() {
+	select {
+	case <-p.stop:
+		return
+	default:
+		close(p.stop)
+	}
+	p.wg.Wait()
+}
+
+// window returns the operations which start in [from, to).
+func (p *probe) window(from, to time.Time) []probeOp {
+	p.Lock()
+	defer p.Unlock()
+	var ret []probeOp
+	for _, op := range p.ops {
+		if !op.start.Before(from) && op.start.Before(to) {
+			ret = append(ret, op)
+		}
+	}
+	return ret
+}
+
+// trim drops the operations which start before t, so that the memory of a long run is bounded by the windows in use.
+func (p *probe) trim(t time.Time) {
+	p.Lock()
+	defer p.Unlock()
+	kept := make([]probeOp, 0, len(p.ops))
+	for _, op := range p.ops {
+		if !op.start.Before(t) {
+			kept = append(kept, op)
+		}
+	}
+	p.ops = kept
+}
+
+// latencyQuantile returns the q quantile of latency of succeeded operations in seconds.
+func latencyQuantile(ops []probeOp, q float64) float64 {
+	var latencies []float64
+	for _, op := range ops {
+		if !op.failed {
+			latencies = append(latencies, op.latency.Seconds())
+		}
+	}
+	if len(latencies) == 0 {
+		return 0
+	}
+	sort.Float64s(latencies)
+	idx := int(q*float64(len(latencies))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(latencies) {
+		idx = len(latencies) - 1
+	}
+	return latencies[idx]
+}
+
+// failedCount returns the number of failed operations.
+func failedCount(ops []probeOp) int {
+	count := 0
+	for _, op := range ops {
+		if op.failed {
+			count++
+		}
+	}
+	return count
+}
+
+// longestGap returns the longest time in [from, to] in which no operation succeeds, in seconds.
+func longestGap(ops []probeOp, from, to time.Time) float64 {
+	var ends []time.Time
+	for _, op := range ops {
+		if !op.failed {
+			ends = append(ends, op.end())
+		}
+	}
+	sort.Slice(ends, func(i, j int) bool { return ends[i].Before(ends[j]) })
+	gap := time.Duration(0)
+	last := from
+	for _, end := range ends {
+		if end.After(to) {
+			break
+		}
+		if end.After(last) {
+			if end.Sub(last) > gap {
+				gap = end.Sub(last)
+			}
+			last = end
+		}
+	}
+	if to.Sub(last) > gap {
+		gap = to.Sub(last)
+	}
+	return gap.Seconds()
+}
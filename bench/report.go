@@ -0,0 +1,99 @@
+package bench
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// diffTitle is the head of a plain text report, which is shown as a diff block.
+func diffTitle() string {
+	return "```diff  \n@@\t\t\tBenchmark diff\t\t\t@@\n" + strings.Repeat("=", 58) + "\n"
+}
+
+// comparisonReport reports each compared metric, grouped by category.
+func comparisonReport(cmp *utils.Comparison) string {
+	plainText := ""
+	for i, d := range cmp.Metrics {
+		if i == 0 || d.Category != cmp.Metrics[i-1].Category {
+			plainText += d.Category + ":  \n"
+		}
+		plainText += reportLine(d)
+	}
+	return plainText
+}
+
+// lastReportOf returns the last report if it is of the case, otherwise nil.
+func (c *cluster) lastReportOf(caseName string) (*WorkloadReport, error) {
+	lastReport, err := c.GetLastReport()
+	if err != nil || lastReport == nil {
+		return nil, err
+	}
+	if lastMeta := parseReportMeta(lastReport.Data); lastMeta == nil || lastMeta.Case != caseName {
+		log.Info("last report is of another case, report as the first one")
+		return nil, nil
+	}
+	return lastReport, nil
+}
+
+// collectReport is the Collect of cases whose report is a tagged struct with a Meta field.
+// The report is compared with the last report of the same case, rendered to artifacts and sent.
+// sections are appended to the plain text, such as the missing metrics.
+func (c *cluster) collectReport(rep interface{}, meta *utils.Metadata, sections ...string) error {
+	lastReport, err := c.lastReportOf(meta.Case)
+	if err != nil {
+		return err
+	}
+	return c.collectReportWith(lastReport, rep, meta, sections...)
+}
+
+// collectReportWith is collectReport with the last report of the case, which is nil for the first report.
+func (c *cluster) collectReportWith(lastReport *WorkloadReport, rep interface{}, meta *utils.Metadata, sections ...string) error {
+	data, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+
+	var plainText string
+	var cmp *utils.Comparison
+	if lastReport == nil {
+		if cmp, err = utils.BaselineComparison(meta.Case, rep); err != nil {
+			return err
+		}
+	} else {
+		stats := utils.NewStats(rep)
+		stats.Title = meta.Case + " stats"
+		stats.Threshold = c.threshold
+		if err := stats.Init(lastReport.Data, string(data)); err != nil {
+			return err
+		}
+		cmp = stats.Compare(meta.Case, c.threshold)
+		// the chart is best effort as other artifacts
+		if path := c.artifactPath("stats.html"); path == "" {
+			log.Warn("artifact dir is not available, stats chart is skipped")
+		} else if err := stats.RenderTo(path); err != nil {
+			log.Warn("failed to render stats", zap.Error(err))
+		}
+		header, err := stats.Report()
+		if err != nil {
+			return err
+		}
+		plainText = diffTitle()
+		plainText += meta.Header()
+		plainText += configDiffReport(parseReportMeta(lastReport.Data), meta)
+		plainText += createArtReport("visualization", "stats", header)
+		plainText += "\n"
+		plainText += comparisonReport(cmp)
+		for _, section := range sections {
+			plainText += section
+		}
+		plainText += "```  \n"
+		log.Info("Merge report success", zap.String("merge result", plainText))
+	}
+	cmp.Meta = meta
+	c.renderComparison(cmp)
+	return c.SendReport(string(data), plainText)
+}
@@ -0,0 +1,88 @@
+package bench
+
+import (
+	"os"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/siddontang/go-mysql/client"
+	"go.uber.org/zap"
+)
+
+// restore restores a prebuilt dataset by BR instead of loading data,
+// the dataset contains a manifest with its id and the row count of each table.
+// The restore_path of a workload is not passed to the container by the platform,
+// so that a workload which selects this generator passes the same path by --restore-path or RESTORE_PATH.
+type restore struct {
+	c        *cluster
+	kind     string
+	path     string
+	endpoint string
+	dataset  *utils.Dataset
+}
+
+func newRestore(c *cluster) generator {
+	kind := os.Getenv("RESTORE_STORAGE")
+	if kind == "" {
+		kind = "s3" // default
+	}
+	return &restore{
+		c:        c,
+		kind:     kind,
+		path:     c.restorePath,
+		endpoint: os.Getenv("S3_ENDPOINT"),
+	}
+}
+
+// Generate is used to restore the dataset, then row counts are verified.
+func (r *restore) Generate() error {
+	storage, err := utils.NewStorage(r.kind, r.path, r.endpoint)
+	if err != nil {
+		return err
+	}
+	dataset, err := utils.ReadDataset(storage)
+	if err != nil {
+		return errors.Annotatef(err, "failed to read dataset from %s", storage.URL())
+	}
+	r.c.SetConfig("generator", "restore")
+	r.c.SetConfig("RESTORE_PATH", r.path)
+	r.c.SetConfig("dataset", dataset.ID)
+
+	conn, err := client.Connect(r.c.tidbAddr, "root", "", "")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// RESTORE requires the tables to be absent
+	for _, db := range dataset.DBs() {
+		if _, err := conn.Execute("DROP DATABASE IF EXISTS " + db); err != nil {
+			return err
+		}
+	}
+	log.Info("restore dataset", zap.String("dataset", dataset.ID), zap.String("url", storage.URL()))
+	if _, err := conn.Execute(utils.RestoreSQL(storage)); err != nil {
+		return errors.Annotatef(err, "failed to restore dataset %s", dataset.ID)
+	}
+	err = dataset.Verify(func(table string) (int64, error) {
+		res, err := conn.Execute("SELECT COUNT(*) FROM " + table)
+		if err != nil {
+			return 0, err
+		}
+		return res.GetInt(0, 0)
+	})
+	if err != nil {
+		return err
+	}
+	log.Info("restored dataset is verified", zap.String("dataset", dataset.ID), zap.Int("tables", len(dataset.Rows)))
+	r.dataset = dataset
+	return nil
+}
+
+// checkTables implements checker.
+func (r *restore) checkTables() ([]string, bool) {
+	if r.dataset == nil {
+		return nil, true
+	}
+	return r.dataset.Tables(), true
+}
@@ -0,0 +1,200 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+const (
+	evictLeaderScheduler = "evict-leader-scheduler"
+	// rollingStepTimeout is the timeout of each step of restarting a store.
+	rollingStepTimeout = 10 * time.Minute
+	rollingWarmup      = 30 * time.Second
+)
+
+func createRollingRestartCase(cluster *cluster) *benchCase {
+	return &benchCase{
+		generator: newYCSB(cluster, "workload-scale-out"),
+		bench:     newRollingRestart(cluster),
+	}
+}
+
+// rollingRestart mimics a rolling upgrade, the leaders of each store are evicted before it is restarted.
+type rollingRestart struct {
+	c         *cluster
+	tolerance float64
+	results   []*utils.RollingRestartOnce
+}
+
+func newRollingRestart(c *cluster) bench {
+	tolerance, err := strconv.ParseFloat(os.Getenv("BALANCE_TOLERANCE"), 64)
+	if err != nil {
+		tolerance = utils.DefaultBalanceTolerance
+	}
+	return &rollingRestart{c: c, tolerance: tolerance}
+}
+
+func (r *rollingRestart) Run() error {
+	r.c.SetConfig("BALANCE_TOLERANCE", strconv.FormatFloat(r.tolerance, 'f', -1, 64))
+	stores, err := r.c.getStoreStats()
+	if err != nil {
+		return err
+	}
+	p, err := r.c.startProbe()
+	if err != nil {
+		return err
+	}
+	defer p.Stop()
+	warmupStart := time.Now()
+	time.Sleep(rollingWarmup)
+
+	start := time.Now()
+	rep := &utils.RollingRestartOnce{
+		PrevP99Latency: latencyQuantile(p.window(warmupStart, start), 0.99),
+		Stores:         make(map[string]utils.RollingRestartStore),
+	}
+	for _, store := range stores {
+		if store.State != utils.StoreUp {
+			continue
+		}
+		storeStart := time.Now()
+		// only the operations of the current store are kept
+		p.trim(storeStart)
+		stats, err := r.restartStore(store)
+		if err != nil {
+			return errors.Annotatef(err, "failed to restart store %d", store.ID)
+		}
+		ops := p.window(storeStart, time.Now())
+		stats.P99Latency = latencyQuantile(ops, 0.99)
+		rep.FailedQueries += failedCount(ops)
+		rep.MaxEvictDuration = math.Max(rep.MaxEvictDuration, stats.EvictDuration)
+		rep.MaxLeaderRestore = math.Max(rep.MaxLeaderRestore, stats.LeaderRestore)
+		rep.MaxP99Latency = math.Max(rep.MaxP99Latency, stats.P99Latency)
+		rep.Stores[strconv.FormatUint(store.ID, 10)] = *stats
+		log.Info("store is restarted", zap.Uint64("store", store.ID), zap.Any("stats", stats))
+	}
+	rep.TotalDuration = time.Since(start).Seconds()
+	r.results = append(r.results, rep)
+	return nil
+}
+
+// restartStore evicts leaders of the store, restarts it, and waits for leaders to come back.
+func (r *rollingRestart) restartStore(store utils.StoreStats) (*utils.RollingRestartStore, error) {
+	stats := &utils.RollingRestartStore{}
+	start := time.Now()
+	if err := r.c.AddScheduler(evictLeaderScheduler, map[string]interface{}{"store_id": store.ID}); err != nil {
+		return nil, err
+	}
+	scheduler := fmt.Sprintf("%s-%d", evictLeaderScheduler, store.ID)
+	removed := false
+	// leaders should not be left evicted if the restart fails
+	defer func() {
+		if removed {
+			return
+		}
+		if err := r.c.RemoveScheduler(scheduler); err != nil {
+			log.Warn("failed to remove scheduler", zap.String("scheduler", scheduler), zap.Error(err))
+		}
+	}()
+	err := waitUntil("leaders to be evicted", rollingStepTimeout, time.Second, func() (bool, error) {
+		s, err := r.c.getStore(store.ID)
+		if err != nil {
+			return false, err
+		}
+		return s.Status.LeaderCount == 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats.EvictDuration = time.Since(start).Seconds()
+
+	prev, err := r.c.getStore(store.ID)
+	if err != nil {
+		return nil, err
+	}
+	start = time.Now()
+	if err := r.c.Restart("tikv", store.Address); err != nil {
+		return nil, err
+	}
+	// the store is restarted if its start time changes, both times are reported by the store
+	// so that the clock of bench is not compared
+	err = waitUntil("store to be up", rollingStepTimeout, time.Second, func() (bool, error) {
+		s, err := r.c.getStore(store.ID)
+		if err != nil {
+			return false, err
+		}
+		return s.Store.StateName == utils.StoreUp && s.Status.StartTS.After(prev.Status.StartTS), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats.RestartDuration = time.Since(start).Seconds()
+
+	start = time.Now()
+	if err := r.c.RemoveScheduler(scheduler); err != nil {
+		return nil, err
+	}
+	removed = true
+	err = waitUntil("leaders to be balanced", rollingStepTimeout, time.Second, func() (bool, error) {
+		stores, err := r.c.getStoreStats()
+		if err != nil {
+			return false, err
+		}
+		var scores []float64
+		for _, s := range stores {
+			if s.State == utils.StoreUp {
+				scores = append(scores, s.LeaderScore)
+			}
+		}
+		return utils.Spread(scores) <= r.tolerance, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats.LeaderRestore = time.Since(start).Seconds()
+	return stats, nil
+}
+
+func (r *rollingRestart) aggregate() (*utils.RollingRestartOnce, error) {
+	agg, err := utils.Aggregate(r.results)
+	if err != nil {
+		return nil, err
+	}
+	rep := agg.(*utils.RollingRestartOnce)
+	// stores may differ between iterations, so that only the last one is kept
+	rep.Stores = r.results[len(r.results)-1].Stores
+	return rep, nil
+}
+
+func (r *rollingRestart) Collect() error {
+	if len(r.results) == 0 {
+		return errors.New("no result to report")
+	}
+	rep, err := r.aggregate()
+	if err != nil {
+		return err
+	}
+	rep.Meta = r.c.runMetadata()
+	return r.c.collectReport(rep, rep.Meta)
+}
+
+// sweepResult implements sweeper.
+func (r *rollingRestart) sweepResult() (interface{}, error) {
+	if len(r.results) == 0 {
+		return nil, errors.New("no result to report")
+	}
+	rep, err := r.aggregate()
+	if err != nil {
+		return nil, err
+	}
+	r.results = nil
+	return rep, nil
+}
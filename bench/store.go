@@ -0,0 +1,117 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// reportStore keeps the history of reports.
+type reportStore interface {
+	// Reports returns at most limit reports, the newest first.
+	Reports(limit int) ([]WorkloadReport, error)
+}
+
+type apiStore struct {
+	c *cluster
+}
+
+// Reports implements reportStore.
+func (s *apiStore) Reports(limit int) ([]WorkloadReport, error) {
+	return s.c.GetReports(limit)
+}
+
+// localStore is a directory of report json files, it is filled when REPORT_STORE is set.
+type localStore struct {
+	dir string
+}
+
+func newLocalStore(dir string) *localStore {
+	return &localStore{dir: dir}
+}
+
+// Save writes a report into the store.
+func (s *localStore) Save(report *WorkloadReport) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	if report.CreatedAt.IsZero() {
+		report.CreatedAt = time.Now()
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("report-%d.json", report.CreatedAt.UnixNano())
+	return ioutil.WriteFile(filepath.Join(s.dir, name), data, 0644)
+}
+
+// Reports implements reportStore.
+func (s *localStore) Reports(limit int) ([]WorkloadReport, error) {
+	var reports []WorkloadReport
+	err := filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var report WorkloadReport
+		if err := json.Unmarshal(data, &report); err != nil || report.Data == "" {
+			log.Debug("skip file which is not a report", zap.String("path", path))
+			return nil
+		}
+		if report.CreatedAt.IsZero() {
+			report.CreatedAt = info.ModTime()
+		}
+		reports = append(reports, report)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newestReports(reports, limit), nil
+}
+
+// reportTime is the start time of the run of a report, it falls back to the time when the report is created.
+func reportTime(report *WorkloadReport) time.Time {
+	if meta := parseReportMeta(report.Data); meta != nil && !meta.StartTime.IsZero() {
+		return meta.StartTime
+	}
+	return report.CreatedAt
+}
+
+// newestReports sorts reports by reportTime, the newest first, and returns at most limit of them.
+func newestReports(reports []WorkloadReport, limit int) []WorkloadReport {
+	type timedReport struct {
+		report WorkloadReport
+		t      time.Time
+	}
+	timed := make([]timedReport, 0, len(reports))
+	for i := range reports {
+		timed = append(timed, timedReport{report: reports[i], t: reportTime(&reports[i])})
+	}
+	sort.SliceStable(timed, func(i, j int) bool {
+		return timed[i].t.After(timed[j].t)
+	})
+	if limit > 0 && len(timed) > limit {
+		timed = timed[:limit]
+	}
+	sorted := make([]WorkloadReport, 0, len(timed))
+	for _, r := range timed {
+		sorted = append(sorted, r.report)
+	}
+	return sorted
+}
@@ -0,0 +1,235 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+const (
+	sweepStoreLimit      = "store-limit"
+	sweepSchedulerPrefix = "scheduler."
+	// storeBalanceRate is the PD config of the default store limit, store limits are restored to it after sweep.
+	storeBalanceRate = "store-balance-rate"
+)
+
+// sweeper is implemented by a bench which supports sweep, it returns the result of runs since the last call.
+type sweeper interface {
+	sweepResult() (interface{}, error)
+}
+
+// Sweep runs the case for each combination of params and compares the results across combinations.
+// run runs all iterations of a combination, reset tells whether the cluster should be restored at first,
+// and apply should be called after every reset, as a reset may restore the config of PD.
+func (c *cluster) Sweep(bc *benchCase, params []utils.SweepParam, run func(reset bool, apply func() error) error) error {
+	s, ok := bc.bench.(sweeper)
+	if !ok {
+		return errors.Errorf("case %s does not support sweep", c.caseName())
+	}
+	restore, err := c.saveSweep(params)
+	if err != nil {
+		return errors.Annotate(err, "failed to save config before sweep")
+	}
+	defer func() {
+		if err := restore(); err != nil {
+			log.Warn("failed to restore config after sweep", zap.Error(err))
+		}
+	}()
+	var points []utils.TrendPoint
+	for i, combination := range utils.Combinations(params) {
+		log.Info("sweep combination", zap.String("combination", combination.Label()))
+		apply := func() error {
+			return errors.Annotatef(c.applySweep(combination), "failed to apply %s", combination.Label())
+		}
+		if err := run(i > 0, apply); err != nil {
+			return err
+		}
+		result, err := s.sweepResult()
+		if err != nil {
+			return err
+		}
+		metrics, err := utils.ParseMetrics(result)
+		if err != nil {
+			return err
+		}
+		points = append(points, utils.TrendPoint{Label: combination.Label(), Metrics: metrics})
+		// render after every combination, so that a failure does not lose finished combinations
+		c.renderSweep(points)
+	}
+	return nil
+}
+
+// saveSweep reads the values of swept keys before sweep, and returns a func which restores them,
+// so that later runs on the cluster do not inherit the config of the last combination.
+func (c *cluster) saveSweep(params []utils.SweepParam) (func() error, error) {
+	var restores []func() error
+	for _, param := range params {
+		key := param.Key
+		switch {
+		case key == sweepStoreLimit:
+			storeLimit := c.storeLimit
+			rate, ok, err := c.getPDConfigValue(storeBalanceRate)
+			if err != nil {
+				return nil, err
+			}
+			restores = append(restores, func() error {
+				c.storeLimit = storeLimit
+				if !ok {
+					log.Warn("store limits are not restored, as PD has no config of the default rate", zap.String("config", storeBalanceRate))
+					return nil
+				}
+				value, err := strconv.ParseFloat(fmt.Sprint(rate), 64)
+				if err != nil {
+					return err
+				}
+				return c.SetAllStoreLimit(value)
+			})
+		case strings.HasPrefix(key, sweepSchedulerPrefix):
+			name := strings.TrimPrefix(key, sweepSchedulerPrefix)
+			schedulers, err := c.getSchedulers()
+			if err != nil {
+				return nil, err
+			}
+			state := "off"
+			for _, s := range schedulers {
+				if s == name {
+					state = "on"
+				}
+			}
+			restores = append(restores, func() error {
+				return c.switchScheduler(name, state)
+			})
+		default:
+			value, ok, err := c.getPDConfigValue(key)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, errors.Errorf("unknown PD config %s", key)
+			}
+			restores = append(restores, func() error {
+				return c.SetPDConfig(key, value)
+			})
+		}
+	}
+	return func() error {
+		var first error
+		for _, restore := range restores {
+			if err := restore(); err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	}, nil
+}
+
+// applySweep applies a combination before each iteration, keys are:
+//
+//	store-limit: the store limit of all stores, as STORE_LIMIT
+//	scheduler.<name>: on adds the scheduler and off removes it
+//	others: PD config, as pd-ctl config set <key> <value>
+func (c *cluster) applySweep(combination utils.SweepCombination) error {
+	for _, kv := range combination {
+		c.SetConfig("sweep."+kv.Key, kv.Value)
+		var err error
+		switch {
+		case kv.Key == sweepStoreLimit:
+			c.storeLimit = kv.Value
+		case strings.HasPrefix(kv.Key, sweepSchedulerPrefix):
+			err = c.switchScheduler(strings.TrimPrefix(kv.Key, sweepSchedulerPrefix), kv.Value)
+		default:
+			err = c.SetPDConfig(kv.Key, configValue(kv.Value))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// switchScheduler adds or removes a scheduler if it is not in the state.
+func (c *cluster) switchScheduler(name, state string) error {
+	var on bool
+	switch state {
+	case "on":
+		on = true
+	case "off":
+	default:
+		return errors.Errorf("scheduler %s should be on or off, but it is %s", name, state)
+	}
+	schedulers, err := c.getSchedulers()
+	if err != nil {
+		return err
+	}
+	exists := false
+	for _, s := range schedulers {
+		exists = exists || s == name
+	}
+	if on && !exists {
+		return c.AddScheduler(name, nil)
+	}
+	if !on && exists {
+		return c.RemoveScheduler(name)
+	}
+	return nil
+}
+
+// configValue converts a value in text to the type of PD config.
+func configValue(value string) interface{} {
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseBool(value); err == nil {
+		return v
+	}
+	return value
+}
+
+// renderSweep writes the table and chart of sweep results to artifacts.
+func (c *cluster) renderSweep(points []utils.TrendPoint) {
+	title := c.caseName() + " sweep"
+	var buf bytes.Buffer
+	if err := utils.RenderSweepTable(&buf, title, points); err != nil {
+		log.Warn("failed to render sweep table", zap.Error(err))
+	} else {
+		c.writeArtifact("sweep.md", buf.Bytes())
+	}
+	buf.Reset()
+	if err := utils.RenderSweepCSV(&buf, points); err != nil {
+		log.Warn("failed to render sweep csv", zap.Error(err))
+	} else {
+		c.writeArtifact("sweep.csv", buf.Bytes())
+	}
+	if path := c.artifactPath("sweep.html"); path != "" {
+		if err := utils.RenderSweep(path, title, points); err != nil {
+			log.Warn("failed to render sweep chart", zap.Error(err))
+		}
+	}
+}
+
+// renderCurve writes the curve of a stepped scale out as a table and line charts.
+func (c *cluster) renderCurve(steps []utils.ScaleOutStep) {
+	points, err := utils.ScaleOutCurve(steps)
+	if err != nil {
+		log.Warn("failed to parse scale out curve", zap.Error(err))
+		return
+	}
+	title := c.caseName() + " curve"
+	var buf bytes.Buffer
+	if err := utils.RenderSweepTable(&buf, title, points); err != nil {
+		log.Warn("failed to render curve table", zap.Error(err))
+	} else {
+		c.writeArtifact("curve.md", buf.Bytes())
+	}
+	if path := c.artifactPath("curve.html"); path != "" {
+		if err := utils.RenderTrend(path, title, points); err != nil {
+			log.Warn("failed to render curve chart", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package bench
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/siddontang/go-mysql/client"
+	"go.uber.org/zap"
+)
+
+const (
+	sysbenchBatchSize = 1000
+	// sysbenchRetryInterval is the sleep after a failed connect or transaction of the mix.
+	sysbenchRetryInterval = 100 * time.Millisecond
+)
+
+// sysbench is a sysbench-like generator, it loads tables with a secondary index,
+// and keeps a mix of transactions running during Run if a mix is set, whether the tables are loaded in this run or not.
+type sysbench struct {
+	c         *cluster
+	tables    int
+	tableSize int
+	threads   int
+	mix       string
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newSysbench(c *cluster) generator {
+	tables, err := strconv.Atoi(os.Getenv("SYSBENCH_TABLES"))
+	if err != nil || tables <= 0 {
+		tables = 16 // default
+	}
+	tableSize, err := strconv.Atoi(os.Getenv("SYSBENCH_TABLE_SIZE"))
+	if err != nil || tableSize <= 0 {
+		tableSize = 10000 // default
+	}
+	threads, err := strconv.Atoi(os.Getenv("SYSBENCH_THREADS"))
+	if err != nil || threads <= 0 {
+		threads = 16 // default
+	}
+	return &sysbench{
+		c:         c,
+		tables:    tables,
+		tableSize: tableSize,
+		threads:   threads,
+		mix:       os.Getenv("SYSBENCH_MIX"),
+	}
+}
+
+// Generate is used to load tables.
+func (s *sysbench) Generate() error {
+	if err := utils.CheckSysbenchMix(s.mix); err != nil {
+		return err
+	}
+	s.c.SetConfig("generator", "sysbench")
+	s.c.SetConfig("SYSBENCH_TABLES", strconv.Itoa(s.tables))
+	s.c.SetConfig("SYSBENCH_TABLE_SIZE", strconv.Itoa(s.tableSize))
+
+	conn, err := client.Connect(s.c.tidbAddr, "root", "", "")
+	if err != nil {
+		return err
+	}
+	_, err = conn.Execute("CREATE DATABASE IF NOT EXISTS " + utils.SysbenchDB)
+	conn.Close()
+	if err != nil {
+		return err
+	}
+	if err := s.parallel(s.loadTable); err != nil {
+		return err
+	}
+	log.Info("sysbench tables are loaded", zap.Int("tables", s.tables), zap.Int("table size", s.tableSize))
+	return nil
+}
+
+// parallel calls f for each table with at most threads connections, it returns the errors of all failed tables.
+func (s *sysbench) parallel(f func(conn *client.Conn, table int) error) error {
+	tables := make(chan int, s.tables)
+	for i := 1; i <= s.tables; i++ {
+		tables <- i
+	}
+	close(tables)
+	errs := make(chan error, s.threads+s.tables)
+	var wg sync.WaitGroup
+	for i := 0; i < s.threads && i < s.tables; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := client.Connect(s.c.tidbAddr, "root", "", utils.SysbenchDB)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer conn.Close()
+			for table := range tables {
+				if err := f(conn, table); err != nil {
+					errs <- errors.Annotatef(err, "table %s", utils.SysbenchTableName(table))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	var msgs []string
+	for err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	if len(msgs) > 0 {
+		return errors.Errorf("%d sysbench errors: %s", len(msgs), strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+func (s *sysbench) loadTable(conn *client.Conn, table int) error {
+	_, err := conn.Execute("DROP TABLE IF EXISTS " + utils.SysbenchTableName(table))
+	if err != nil {
+		return err
+	}
+	if _, err = conn.Execute(utils.SysbenchCreateTable(table)); err != nil {
+		return err
+	}
+	for start := 1; start <= s.tableSize; start += sysbenchBatchSize {
+		end := start + sysbenchBatchSize - 1
+		if end > s.tableSize {
+			end = s.tableSize
+		}
+		if _, err = conn.Execute(utils.SysbenchInsert(table, start, end, s.tableSize)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartWorkload implements workload, the mix is started on the loaded tables.
+func (s *sysbench) StartWorkload() error {
+	if err := utils.CheckSysbenchMix(s.mix); err != nil || s.mix == "" {
+		return err
+	}
+	s.c.SetConfig("SYSBENCH_MIX", s.mix)
+	s.c.SetConfig("SYSBENCH_THREADS", strconv.Itoa(s.threads))
+	s.stop = make(chan struct{})
+	for i := 0; i < s.threads; i++ {
+		s.wg.Add(1)
+		go s.runMix()
+	}
+	log.Info("sysbench mix is started", zap.String("mix", s.mix), zap.Int("threads", s.threads))
+	return nil
+}
+
+func (s *sysbench) runMix() {
+	defer s.wg.Done()
+	var conn *client.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		var err error
+		if conn == nil {
+			if conn, err = client.Connect(s.c.tidbAddr, "root", "", utils.SysbenchDB); err != nil {
+				log.Debug("sysbench failed to connect", zap.Error(err))
+				time.Sleep(sysbenchRetryInterval)
+				continue
+			}
+		}
+		if err = s.transaction(conn); err != nil {
+			log.Debug("sysbench transaction failed", zap.String("mix", s.mix), zap.Error(err))
+			conn.Close()
+			conn = nil
+			// TiDB may keep rejecting statements, such as during a scale in or a leader switch
+			time.Sleep(sysbenchRetryInterval)
+		}
+	}
+}
+
+// transaction runs a transaction of the mix.
+func (s *sysbench) transaction(conn *client.Conn) error {
+	queries, err := utils.SysbenchTransaction(s.mix, s.tables, s.tableSize)
+	if err != nil {
+		return err
+	}
+	for _, query := range queries {
+		if _, err := conn.Execute(query); err != nil {
+			if len(queries) > 1 {
+				_, _ = conn.Execute("ROLLBACK")
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTables implements checker, tables are static unless the mix writes.
+func (s *sysbench) checkTables() ([]string, bool) {
+	tables := make([]string, 0, s.tables)
+	for i := 1; i <= s.tables; i++ {
+		tables = append(tables, utils.SysbenchDB+"."+utils.SysbenchTableName(i))
+	}
+	return tables, s.mix == "" || s.mix == utils.SysbenchPointSelect
+}
+
+// StopWorkload implements workload.
+func (s *sysbench) StopWorkload() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	s.wg.Wait()
+	s.stop = nil
+}
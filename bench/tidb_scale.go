@@ -0,0 +1,188 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/siddontang/go-mysql/client"
+	"go.uber.org/zap"
+)
+
+func createTiDBScaleOutCase(cluster *cluster) *benchCase {
+	return &benchCase{
+		generator: newEmptyGenerator(),
+		bench:     newTiDBScaleOut(cluster),
+	}
+}
+
+// tidbScaleOut adds TiDB instances, then opens new connections to the load balancer
+// in front of TiDB and reports how they are distributed over the instances.
+type tidbScaleOut struct {
+	c           *cluster
+	num         int
+	connections int
+	// lbAddr is the address of the load balancer, the TiDB address of the cluster is one instance
+	lbAddr string
+	// added is the addresses of the instances which are added since the last reset
+	added   []string
+	results []*utils.TiDBScaleOutOnce
+}
+
+func newTiDBScaleOut(c *cluster) bench {
+	num, err := strconv.Atoi(os.Getenv("SCALE_NUM"))
+	if err != nil {
+		num = 1 // default
+	}
+	connections, err := strconv.Atoi(os.Getenv("TIDB_CONNECTIONS"))
+	if err != nil {
+		connections = 64 // default
+	}
+	return &tidbScaleOut{c: c, num: num, connections: connections, lbAddr: os.Getenv("TIDB_LB_ADDR")}
+}
+
+func (s *tidbScaleOut) Run() error {
+	if s.lbAddr == "" {
+		return errors.New("TIDB_LB_ADDR is required, connections to one TiDB instance are not distributed")
+	}
+	s.c.SetConfig("TIDB_LB_ADDR", s.lbAddr)
+	s.c.SetConfig("SCALE_NUM", strconv.Itoa(s.num))
+	s.c.SetConfig("TIDB_CONNECTIONS", strconv.Itoa(s.connections))
+	start := time.Now()
+	added, err := s.c.ScaleOut(componentTiDB, s.num)
+	if err != nil {
+		return err
+	}
+	s.added = append(s.added, added...)
+	rep := &utils.TiDBScaleOutOnce{ReadyTime: time.Since(start).Seconds()}
+	instances, err := s.c.getTiDBInstances()
+	if err != nil {
+		return err
+	}
+	rep.Instances = len(instances)
+	if rep.Shares, err = s.distribute(); err != nil {
+		return err
+	}
+	var counts []float64
+	for _, share := range rep.Shares {
+		counts = append(counts, float64(share.Connections))
+		if share.Share > rep.MaxShare {
+			rep.MaxShare = share.Share
+		}
+	}
+	// instances which serve no connection are counted in the spread too
+	for i := len(rep.Shares); i < rep.Instances; i++ {
+		rep.Unreached++
+		counts = append(counts, 0)
+	}
+	rep.ConnectionSpread = utils.Spread(counts)
+	log.Info("tidb instances are scaled out", zap.Any("stats", rep))
+	s.results = append(s.results, rep)
+	return nil
+}
+
+// distribute opens connections to the load balancer at the same time, and returns
+// the connections served by each instance, which is identified by its host and port.
+func (s *tidbScaleOut) distribute() (map[string]utils.TiDBShare, error) {
+	conns := make([]*client.Conn, 0, s.connections)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	counts := make(map[string]int)
+	for i := 0; i < s.connections; i++ {
+		conn, err := client.Connect(s.lbAddr, "root", "", "")
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, conn)
+		res, err := conn.Execute("SELECT @@hostname, @@port")
+		if err != nil {
+			return nil, err
+		}
+		host, err := res.GetString(0, 0)
+		if err != nil {
+			return nil, err
+		}
+		port, err := res.GetInt(0, 1)
+		if err != nil {
+			return nil, err
+		}
+		counts[fmt.Sprintf("%s:%d", host, port)]++
+	}
+	shares := make(map[string]utils.TiDBShare, len(counts))
+	for instance, count := range counts {
+		shares[instance] = utils.TiDBShare{Connections: count, Share: float64(count) / float64(s.connections)}
+	}
+	return shares, nil
+}
+
+// Reset removes the added instances, so that the next iteration starts from the same instances.
+func (s *tidbScaleOut) Reset() error {
+	added := s.added
+	s.added = nil
+	if len(added) == 0 {
+		return nil
+	}
+	return s.c.ScaleIn(componentTiDB, added)
+}
+
+// shareReport reports the connections served by each instance.
+func shareReport(shares map[string]utils.TiDBShare) string {
+	if len(shares) == 0 {
+		return ""
+	}
+	instances := make([]string, 0, len(shares))
+	for instance := range shares {
+		instances = append(instances, instance)
+	}
+	sort.Strings(instances)
+	plainText := "connections:  \n"
+	for _, instance := range instances {
+		share := shares[instance]
+		plainText += fmt.Sprintf(" \t* %s: %d connections, share %.2f  \n", instance, share.Connections, share.Share)
+	}
+	return plainText
+}
+
+func (s *tidbScaleOut) aggregate() (*utils.TiDBScaleOutOnce, error) {
+	agg, err := utils.Aggregate(s.results)
+	if err != nil {
+		return nil, err
+	}
+	rep := agg.(*utils.TiDBScaleOutOnce)
+	// instances may differ between iterations, so that only the last one is kept
+	rep.Shares = s.results[len(s.results)-1].Shares
+	return rep, nil
+}
+
+func (s *tidbScaleOut) Collect() error {
+	if len(s.results) == 0 {
+		return errors.New("no result to report")
+	}
+	rep, err := s.aggregate()
+	if err != nil {
+		return err
+	}
+	rep.Meta = s.c.runMetadata()
+	return s.c.collectReport(rep, rep.Meta, shareReport(rep.Shares))
+}
+
+// sweepResult implements sweeper.
+func (s *tidbScaleOut) sweepResult() (interface{}, error) {
+	if len(s.results) == 0 {
+		return nil, errors.New("no result to report")
+	}
+	rep, err := s.aggregate()
+	if err != nil {
+		return nil, err
+	}
+	s.results = nil
+	return rep, nil
+}
@@ -0,0 +1,131 @@
+package bench
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+)
+
+const goTPCPath = "/bin/go-tpc"
+
+var tpccTables = []string{"warehouse", "district", "customer", "history", "new_order", "orders", "order_line", "item", "stock"}
+
+func createTPCCCase(cluster *cluster) *benchCase {
+	t := newTPCC(cluster)
+	return &benchCase{
+		generator: t,
+		bench:     t,
+	}
+}
+
+// tpcc prepares warehouses by go-tpc as the generator, and runs TPC-C as the bench.
+type tpcc struct {
+	c          *cluster
+	dbName     string
+	warehouses int
+	threads    int
+	duration   string
+	results    []*utils.TPCCOnce
+}
+
+func newTPCC(c *cluster) *tpcc {
+	warehouses, err := strconv.Atoi(os.Getenv("TPCC_WAREHOUSES"))
+	if err != nil || warehouses <= 0 {
+		warehouses = 10 // default
+	}
+	threads, err := strconv.Atoi(os.Getenv("TPCC_THREADS"))
+	if err != nil || threads <= 0 {
+		threads = 16 // default
+	}
+	duration := os.Getenv("TPCC_DURATION")
+	if duration == "" {
+		duration = "10m"
+	}
+	return &tpcc{
+		c:          c,
+		dbName:     "tpcc",
+		warehouses: warehouses,
+		threads:    threads,
+		duration:   duration,
+	}
+}
+
+func (t *tpcc) command(action string, extra ...string) (*utils.Command, error) {
+	host, port, err := splitAddr(t.c.tidbAddr)
+	if err != nil {
+		return nil, err
+	}
+	// the first of args is argv[0]
+	args := []string{"go-tpc", "tpcc", action, "--warehouses", strconv.Itoa(t.warehouses), "-H", host, "-P", port,
+		"-D", t.dbName, "-T", strconv.Itoa(t.threads)}
+	args = append(args, extra...)
+	return utils.NewCommand(goTPCPath, args...).SetLogFile(t.c.artifactPath("go-tpc-" + action + ".log")), nil
+}
+
+// Generate is used to prepare warehouses.
+func (t *tpcc) Generate() error {
+	t.c.SetConfig("TPCC_WAREHOUSES", strconv.Itoa(t.warehouses))
+	cmd, err := t.command("prepare")
+	if err != nil {
+		return err
+	}
+	_, err = cmd.Run()
+	return err
+}
+
+// checkTables implements checker, tables are written by Run.
+func (t *tpcc) checkTables() ([]string, bool) {
+	tables := make([]string, 0, len(tpccTables))
+	for _, table := range tpccTables {
+		tables = append(tables, t.dbName+"."+table)
+	}
+	return tables, false
+}
+
+func (t *tpcc) Run() error {
+	t.c.SetConfig("TPCC_WAREHOUSES", strconv.Itoa(t.warehouses))
+	t.c.SetConfig("TPCC_THREADS", strconv.Itoa(t.threads))
+	t.c.SetConfig("TPCC_DURATION", t.duration)
+	cmd, err := t.command("run", "--time", t.duration)
+	if err != nil {
+		return err
+	}
+	out, err := cmd.Run()
+	if err != nil {
+		return err
+	}
+	rep, err := utils.ParseTPCCOutput(out)
+	if err != nil {
+		return err
+	}
+	t.results = append(t.results, rep)
+	return nil
+}
+
+func (t *tpcc) Collect() error {
+	if len(t.results) == 0 {
+		return errors.New("no result to report")
+	}
+	agg, err := utils.Aggregate(t.results)
+	if err != nil {
+		return err
+	}
+	rep := agg.(*utils.TPCCOnce)
+	rep.Meta = t.c.runMetadata()
+	return t.c.collectReport(rep, rep.Meta)
+}
+
+// sweepResult implements sweeper.
+func (t *tpcc) sweepResult() (interface{}, error) {
+	if len(t.results) == 0 {
+		return nil, errors.New("no result to report")
+	}
+	rep, err := utils.Aggregate(t.results)
+	if err != nil {
+		return nil, err
+	}
+	t.results = nil
+	return rep, nil
+}
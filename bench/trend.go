@@ -0,0 +1,119 @@
+package bench
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// reportTypes is the report struct of each case, reports of other cases are parsed as generic json.
+var reportTypes = map[string]interface{}{
+	"scale-out":               utils.ScaleOutOnce{},
+	"pd-leader-switch":        utils.PDLeaderSwitchOnce{},
+	"rolling-restart":         utils.RollingRestartOnce{},
+	"tpcc":                    utils.TPCCOnce{},
+	"elasticity":              utils.ElasticityOnce{},
+	"heterogeneous-scale-out": utils.HeterogeneousOnce{},
+	"placement-rules":         utils.PlacementOnce{},
+	"pd-scale-out":            utils.PDScaleOutOnce{},
+	"tidb-scale-out":          utils.TiDBScaleOutOnce{},
+}
+
+// reportMeta is used to get the metadata of any report which has a Meta field.
+type reportMeta struct {
+	Meta *utils.Metadata `json:"Meta"`
+}
+
+func parseReportMeta(data string) *utils.Metadata {
+	var m reportMeta
+	if err := json.Unmarshal([]byte(data), &m); err != nil {
+		return nil
+	}
+	return m.Meta
+}
+
+// parseReportMetrics returns the metrics of a report, it returns an error if the report is not json.
+func parseReportMetrics(caseName, data string) ([]utils.Metric, error) {
+	if proto, ok := reportTypes[caseName]; ok {
+		v := reflect.New(reflect.TypeOf(proto)).Interface()
+		if err := json.Unmarshal([]byte(data), v); err != nil {
+			return nil, err
+		}
+		return utils.ParseReportMetrics(v)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &m); err != nil {
+		return nil, err
+	}
+	delete(m, "Meta")
+	return utils.ParseMetrics(m)
+}
+
+// TrendOption is the option of Trend.
+type TrendOption struct {
+	Case  string
+	Limit int
+	// Store is the directory of a local report store, reports are pulled from the API server if it is empty.
+	Store  string
+	Output string
+}
+
+func trendLabel(report *WorkloadReport, meta *utils.Metadata) string {
+	t := report.CreatedAt
+	if meta != nil && !meta.EndTime.IsZero() {
+		t = meta.EndTime
+	}
+	label := t.Format("2006-01-02 15:04")
+	if meta != nil {
+		for _, component := range []string{"pd", "tikv"} {
+			if version, ok := meta.Versions[component]; ok {
+				label += "\n" + component + " " + version
+			}
+		}
+	}
+	return label
+}
+
+// Trend renders the metrics of the last reports of a case over time.
+func (c *cluster) Trend(opt TrendOption) error {
+	var store reportStore = &apiStore{c: c}
+	if opt.Store != "" {
+		store = newLocalStore(opt.Store)
+	}
+	// reports of other cases may be in the store, so that all reports are pulled before filter.
+	reports, err := store.Reports(0)
+	if err != nil {
+		return err
+	}
+	var points []utils.TrendPoint
+	for i := range reports {
+		if opt.Limit > 0 && len(points) >= opt.Limit {
+			break
+		}
+		// a report of unknown case may still decode as the case, so that it is skipped too
+		meta := parseReportMeta(reports[i].Data)
+		if meta == nil || meta.Case != opt.Case {
+			continue
+		}
+		metrics, err := parseReportMetrics(opt.Case, reports[i].Data)
+		if err != nil {
+			log.Warn("skip report which has no metrics", zap.Uint("id", reports[i].ID), zap.Error(err))
+			continue
+		}
+		points = append(points, utils.TrendPoint{Label: trendLabel(&reports[i], meta), Metrics: metrics})
+	}
+	if len(points) == 0 {
+		return errors.Errorf("no report of case %s", opt.Case)
+	}
+	// reports are the newest first, but the trend is from old to new
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+	log.Info("render trend", zap.String("case", opt.Case), zap.Int("reports", len(points)), zap.String("output", opt.Output))
+	return utils.RenderTrend(opt.Output, strings.TrimSpace(opt.Case+" trend"), points)
+}
@@ -0,0 +1,125 @@
+package bench
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lhy1024/bench/utils"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/siddontang/go-mysql/client"
+	"go.uber.org/zap"
+)
+
+const verifySamples = 16
+
+// checker is implemented by a generator whose tables can be verified after Run.
+// Tables are static if no workload writes them during Run, only static tables are
+// compared with the snapshot, the others are only checked by ADMIN CHECK TABLE.
+type checker interface {
+	checkTables() (tables []string, static bool)
+}
+
+// Snapshot records row counts, checksums and sample rows of static tables of the generator in the case.
+// It should be taken right after Generate, so that data lost since then is detected by Verify.
+func (c *cluster) Snapshot(bc *benchCase) error {
+	bc.snapshots = nil
+	g, ok := bc.generator.(checker)
+	if !ok {
+		return errors.Errorf("generator of case %s does not support verify", c.caseName())
+	}
+	tables, static := g.checkTables()
+	if !static {
+		return nil
+	}
+	conn, err := client.Connect(c.tidbAddr, "root", "", "")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	for _, table := range tables {
+		snapshot, err := snapshotTable(conn, table)
+		if err != nil {
+			return errors.Annotatef(err, "failed to snapshot %s", table)
+		}
+		res, err := conn.Execute(fmt.Sprintf("SELECT * FROM %s ORDER BY RAND() LIMIT %d", table, verifySamples))
+		if err != nil {
+			return errors.Annotatef(err, "failed to sample %s", table)
+		}
+		for _, field := range res.Fields {
+			snapshot.Columns = append(snapshot.Columns, string(field.Name))
+		}
+		for i := range res.Values {
+			sample := make([]interface{}, 0, len(res.Values[i]))
+			for j := range res.Values[i] {
+				sample = append(sample, res.Values[i][j].Value())
+			}
+			snapshot.Samples = append(snapshot.Samples, sample)
+		}
+		bc.snapshots = append(bc.snapshots, snapshot)
+	}
+	return nil
+}
+
+func snapshotTable(conn *client.Conn, table string) (*utils.TableSnapshot, error) {
+	snapshot := &utils.TableSnapshot{Table: table}
+	res, err := conn.Execute("SELECT COUNT(*) FROM " + table)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Rows, err = res.GetInt(0, 0); err != nil {
+		return nil, err
+	}
+	res, err = conn.Execute("ADMIN CHECKSUM TABLE " + table)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Checksum, err = res.GetUintByName(0, "Checksum_crc64_xor"); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Verify checks tables of the generator after Run, the run is marked as failed if anything diverges.
+func (c *cluster) Verify(bc *benchCase) error {
+	g, ok := bc.generator.(checker)
+	if !ok {
+		return errors.Errorf("generator of case %s does not support verify", c.caseName())
+	}
+	tables, _ := g.checkTables()
+	conn, err := client.Connect(c.tidbAddr, "root", "", "")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var divergences []string
+	for _, table := range tables {
+		if _, err := conn.Execute("ADMIN CHECK TABLE " + table); err != nil {
+			divergences = append(divergences, fmt.Sprintf("%s: %v", table, err))
+		}
+	}
+	for _, last := range bc.snapshots {
+		cur, err := snapshotTable(conn, last.Table)
+		if err != nil {
+			return errors.Annotatef(err, "failed to snapshot %s", last.Table)
+		}
+		divergences = append(divergences, last.Diverge(cur)...)
+		query := last.SampleQuery()
+		for _, sample := range last.Samples {
+			res, err := conn.Execute(query, sample...)
+			if err != nil {
+				return errors.Annotatef(err, "failed to read back %s", last.Table)
+			}
+			if n, err := res.GetInt(0, 0); err != nil || n == 0 {
+				divergences = append(divergences, fmt.Sprintf("%s: sample %v is not read back", last.Table, sample))
+			}
+		}
+	}
+	if len(divergences) > 0 {
+		err := errors.Errorf("data diverges after run: %s", strings.Join(divergences, "; "))
+		c.SetError(err)
+		return err
+	}
+	log.Info("data is verified", zap.Strings("tables", tables), zap.Int("snapshots", len(bc.snapshots)))
+	return nil
+}
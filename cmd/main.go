@@ -2,8 +2,12 @@ package main
 
 import (
 	"flag"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/lhy1024/bench/bench"
+	"github.com/lhy1024/bench/utils"
 	"github.com/pingcap/log"
 	"go.uber.org/zap"
 )
@@ -11,36 +15,141 @@ import (
 var (
 	withBench    = flag.Bool("bench", true, "bench mode, it will bench this workload-scale-out")
 	withGenerate = flag.Bool("generate", false, "generate mode,it will allow bench in empty database or only generate data")
-	caseName     = flag.String("case", "", "case name, support list:scale-out, tpcc")
+	caseName     = flag.String("case", "", "case name, support list: scale-out, sim-import, pd-leader-switch, rolling-restart, tpcc, elasticity, heterogeneous-scale-out, placement-rules, pd-scale-out, tidb-scale-out")
+	iterations   = flag.Int("iterations", 1, "repeat generate and bench for iterations, and aggregate the reports")
+	reset        = flag.String("reset", "", "command to restore the cluster between iterations, default is the reset step of case")
+	reportFormat = flag.String("report-format", "json", "comma separated machine-readable report formats written to artifacts, support list: csv, json, junit, markdown")
+	threshold    = flag.Float64("threshold", utils.DefaultThreshold, "percentage of delta under which a metric is unchanged")
+	artifactDir  = flag.String("artifact-dir", "", "directory to collect artifacts, default is $ARTIFACT_DIR or /artifacts")
+	generator    = flag.String("generator", "", "generator of generate mode, default is the generator of case, support list: ycsb, sysbench, restore, empty")
+	restorePath  = flag.String("restore-path", "", "path of the dataset restored by the restore generator, default is $RESTORE_PATH")
+	verify       = flag.Bool("verify", false, "verify data of the generator after each bench, the run fails if data diverges")
+	sweep        = flag.String("sweep", "", "matrix of pd configs to sweep, such as \"leader-schedule-limit=4,8;store-limit=200,2000;scheduler.balance-region-scheduler=on,off\"")
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "trend" {
+		runTrend(os.Args[2:])
+		return
+	}
 	flag.Parse()
 	cluster := bench.NewCluster()
+	if *artifactDir != "" {
+		cluster.SetArtifactDir(*artifactDir)
+	}
+	if *restorePath != "" {
+		cluster.SetRestorePath(*restorePath)
+	}
+	var formats []string
+	if *reportFormat != "" {
+		formats = strings.Split(*reportFormat, ",")
+	}
+	if err := cluster.SetReportFormats(formats); err != nil {
+		log.Fatal("error with report format", zap.Error(err))
+	}
+	cluster.SetThreshold(*threshold)
 	benchCases := bench.NewBenches(cluster)
 	benchCase := benchCases.GetBench(*caseName)
 	if benchCase == nil {
 		log.Fatal("error with case name", zap.String("name", *caseName), zap.Strings("support list", benchCases.SupportList()))
 		return
 	}
+	if *generator != "" {
+		if err := benchCases.SetGenerator(*caseName, *generator); err != nil {
+			log.Fatal("error with generator", zap.Error(err))
+		}
+	}
 
-	if *withGenerate {
-		err := benchCase.Generate()
-		if err != nil {
-			log.Fatal("failed when generate data", zap.Error(err))
+	var params []utils.SweepParam
+	if *sweep != "" {
+		var err error
+		if params, err = utils.ParseSweep(*sweep); err != nil {
+			log.Fatal("error with sweep", zap.Error(err))
 		}
-		log.Info("generate data finish")
 	}
 
-	if *withBench {
-		err := benchCase.Run()
-		if err != nil {
-			log.Fatal("failed when bench", zap.Error(err))
+	cluster.BeginRun(*caseName)
+	if *iterations > 1 {
+		cluster.SetConfig("iterations", strconv.Itoa(*iterations))
+	}
+	// verifyErr fails the run after the report is collected, so that the failed report is kept
+	var verifyErr error
+	// runIterations runs all iterations, restore tells whether the cluster should be reset before the first one,
+	// apply is called before each iteration after the reset if it is not nil
+	runIterations := func(restore bool, apply func() error) error {
+		for i := 0; i < *iterations; i++ {
+			if i > 0 || restore {
+				err := benchCase.Reset(*reset)
+				if err != nil {
+					log.Error("failed when reset", zap.Int("iteration", i), zap.Error(err))
+					return err
+				}
+			}
+			if apply != nil {
+				if err := apply(); err != nil {
+					log.Error("failed when apply sweep", zap.Int("iteration", i), zap.Error(err))
+					return err
+				}
+			}
+
+			if *withGenerate {
+				err := benchCase.Generate()
+				if err != nil {
+					log.Error("failed when generate data", zap.Error(err))
+					return err
+				}
+				log.Info("generate data finish", zap.Int("iteration", i))
+			}
+			// the snapshot is taken after generate, or before run on existing data if generate is skipped
+			if *verify && (*withGenerate || (*withBench && i == 0)) {
+				if err := cluster.Snapshot(benchCase); err != nil {
+					log.Error("failed when snapshot data", zap.Error(err))
+					return err
+				}
+			}
+
+			if *withBench {
+				err := benchCase.Run()
+				if err != nil {
+					log.Error("failed when bench", zap.Error(err))
+					return err
+				}
+				if *verify {
+					if err := cluster.Verify(benchCase); err != nil {
+						log.Error("failed when verify data", zap.Error(err))
+						verifyErr = err
+					}
+				}
+				log.Info("bench iteration finish", zap.Int("iteration", i))
+			}
 		}
-		err = benchCase.Collect()
-		if err != nil {
-			log.Fatal("failed when collect report", zap.Error(err))
+		return nil
+	}
+	err := func() error {
+		if len(params) > 0 {
+			// results of combinations are compared with each other instead of the last report
+			if err := cluster.Sweep(benchCase, params, runIterations); err != nil {
+				return err
+			}
+			return verifyErr
 		}
-		log.Info("bench finish")
+		if err := runIterations(false, nil); err != nil {
+			return err
+		}
+		if *withBench {
+			err := benchCase.Collect()
+			if err != nil {
+				log.Error("failed when collect report", zap.Error(err))
+				return err
+			}
+			log.Info("bench finish")
+		}
+		return verifyErr
+	}()
+	if collectErr := cluster.CollectArtifacts(err); collectErr != nil {
+		log.Warn("failed when collect artifacts", zap.Error(collectErr))
+	}
+	if err != nil {
+		log.Fatal("bench failed", zap.Error(err))
 	}
 }
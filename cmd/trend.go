@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/lhy1024/bench/bench"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// runTrend is the trend command, it renders the history of reports of a case.
+func runTrend(args []string) {
+	fs := flag.NewFlagSet("trend", flag.ExitOnError)
+	caseName := fs.String("case", "", "case name of reports")
+	limit := fs.Int("limit", 30, "number of the last reports to render")
+	store := fs.String("store", "", "directory of local report store, reports are pulled from the API server if it is empty")
+	output := fs.String("output", "trend.html", "output html file")
+	if err := fs.Parse(args); err != nil {
+		log.Fatal("error with trend args", zap.Error(err))
+	}
+
+	cluster := bench.NewCluster()
+	err := cluster.Trend(bench.TrendOption{
+		Case:   *caseName,
+		Limit:  *limit,
+		Store:  *store,
+		Output: *output,
+	})
+	if err != nil {
+		log.Fatal("failed when render trend", zap.Error(err))
+	}
+	log.Info("render trend finish", zap.String("output", *output))
+}
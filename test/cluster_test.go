@@ -1,10 +1,17 @@
 package test
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/lhy1024/bench/bench"
+	"github.com/lhy1024/bench/utils"
 	. "github.com/pingcap/check"
 )
 
@@ -18,17 +25,39 @@ var _ = Suite(&testClusterSuite{})
 
 func (s *testClusterSuite) SetUpSuite(c *C) {
 	go mockServer()
+	go mockPDServer()
 	time.Sleep(1 * time.Second)
 }
 
 func (s *testClusterSuite) TestScaleOut(c *C) {
 	cluster := bench.NewCluster()
 	cluster.SetAPIServer("http://" + mockServerAddr)
-	cluster.SetID("1")
+	cluster.SetID(mockClusterID)
 	cluster.SetName("test")
 
 	err := cluster.AddStore()
 	c.Assert(err, IsNil)
+	platform.takeCalls()
+	err = cluster.Restart("tikv", "127.0.0.1:20160")
+	c.Assert(err, IsNil)
+	c.Assert(platform.takeCalls(), DeepEquals, []string{"restart 1 tikv 127.0.0.1:20160"})
+	cluster.SetID("2")
+	c.Assert(cluster.Restart("tikv", "127.0.0.1:20160"), NotNil)
+	c.Assert(platform.takeCalls(), HasLen, 0)
+	cluster.SetID(mockClusterID)
+	err = cluster.ScaleInInstance("tikv", "127.0.0.1:20160")
+	c.Assert(err, IsNil)
+	c.Assert(platform.takeCalls(), DeepEquals, []string{"scale_in 1 tikv 127.0.0.1:20160"})
+	c.Assert(cluster.ScaleInInstance("tiflash", "127.0.0.1:20160"), NotNil)
+	c.Assert(platform.takeCalls(), HasLen, 0)
+
+	// new stores are added on machines of the instance type and labels
+	err = cluster.AddStoreOf(&bench.ResourceSelector{InstanceType: "i3.2xlarge", Labels: map[string]string{"zone": "z1"}})
+	c.Assert(err, IsNil)
+	err = cluster.AddStoreOf(&bench.ResourceSelector{InstanceType: "m5.2xlarge"})
+	c.Assert(err, ErrorMatches, "no available resources of instance_type=m5.2xlarge")
+	err = cluster.AddStoreOf(&bench.ResourceSelector{Labels: map[string]string{"zone": "z2"}})
+	c.Assert(err, NotNil)
 }
 
 func (s *testClusterSuite) TestReport(c *C) {
@@ -36,6 +65,7 @@ func (s *testClusterSuite) TestReport(c *C) {
 	cluster.SetAPIServer("http://" + mockServerAddr)
 	cluster.SetID("1")
 	cluster.SetName("test")
+	cluster.SetArtifactDir(c.MkDir())
 
 	lastReport, err := cluster.GetLastReport()
 	c.Assert(err, IsNil)
@@ -48,3 +78,159 @@ func (s *testClusterSuite) TestReport(c *C) {
 	c.Assert(lastReport, NotNil)
 	c.Assert(lastReport.Data, Equals, report)
 }
+
+func (s *testClusterSuite) TestTrend(c *C) {
+	cluster := bench.NewCluster()
+	cluster.SetAPIServer("http://" + mockServerAddr)
+	cluster.SetID("2")
+	cluster.SetArtifactDir(c.MkDir())
+	store := c.MkDir()
+	cluster.SetReportStore(store)
+
+	for i, interval := range []int{100, 90, 80} {
+		meta := utils.NewMetadata("scale-out")
+		meta.Versions["pd"] = fmt.Sprintf("v4.0.%d", i)
+		meta.StartTime = time.Now().Add(time.Duration(i) * time.Minute)
+		meta.EndTime = meta.StartTime
+		data, err := json.Marshal(&utils.ScaleOutOnce{BalanceInterval: interval, Meta: meta})
+		c.Assert(err, IsNil)
+		c.Assert(cluster.SendReport(string(data), ""), IsNil)
+	}
+	// report of other case is skipped
+	other, err := json.Marshal(&utils.ScaleOutOnce{Meta: utils.NewMetadata("sim-import")})
+	c.Assert(err, IsNil)
+	c.Assert(cluster.SendReport(string(other), ""), IsNil)
+	// report of unknown case is skipped
+	unknown, err := json.Marshal(&utils.ScaleOutOnce{BalanceInterval: 4242})
+	c.Assert(err, IsNil)
+	c.Assert(cluster.SendReport(string(unknown), ""), IsNil)
+
+	for _, source := range []string{"", store} {
+		output := filepath.Join(c.MkDir(), "trend.html")
+		err := cluster.Trend(bench.TrendOption{Case: "scale-out", Limit: 2, Store: source, Output: output})
+		c.Assert(err, IsNil)
+		data, err := ioutil.ReadFile(output)
+		c.Assert(err, IsNil)
+		html := string(data)
+		c.Assert(strings.Contains(html, "BalanceInterval (s)"), IsTrue)
+		c.Assert(strings.Contains(html, "pd v4.0.2"), IsTrue)
+		c.Assert(strings.Contains(html, "pd v4.0.0"), IsFalse)
+		c.Assert(strings.Index(html, "pd v4.0.1") < strings.Index(html, "pd v4.0.2"), IsTrue)
+	}
+
+	output := filepath.Join(c.MkDir(), "trend.html")
+	c.Assert(cluster.Trend(bench.TrendOption{Case: "scale-out", Store: store, Output: output}), IsNil)
+	data, err := ioutil.ReadFile(output)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(data), "4242"), IsFalse)
+
+	err = cluster.Trend(bench.TrendOption{Case: "tpcc", Store: store, Output: filepath.Join(c.MkDir(), "trend.html")})
+	c.Assert(err, NotNil)
+}
+
+func (s *testClusterSuite) TestLeaderPinRules(c *C) {
+	start, end := utils.TableKeyRange(45)
+	rules := bench.LeaderPinRules(start, end, "z1")
+	// the rules override the default rule in the range, so that they should keep 3 peers with 1 leader
+	peers, leaders := 0, 0
+	for _, rule := range rules {
+		c.Assert(rule.GroupID, Equals, rules[0].GroupID)
+		c.Assert(rule.Index, Equals, rules[0].Index)
+		c.Assert(rule.StartKeyHex, Equals, "7480000000000000ff2d00000000000000f8")
+		c.Assert(rule.EndKeyHex, Equals, "7480000000000000ff2e00000000000000f8")
+		peers += rule.Count
+		if rule.Role == "leader" {
+			leaders += rule.Count
+			c.Assert(rule.LabelConstraints, DeepEquals, []bench.LabelConstraint{{Key: "zone", Op: "in", Values: []string{"z1"}}})
+		}
+	}
+	c.Assert(peers, Equals, 3)
+	c.Assert(leaders, Equals, 1)
+}
+
+func (s *testClusterSuite) TestResetCommand(c *C) {
+	cluster := bench.NewCluster()
+	bc := bench.NewBenches(cluster).GetBench("scale-out")
+	dir := c.MkDir()
+	// the reset command is run by shell with its arguments
+	c.Assert(bc.Reset("touch "+filepath.Join(dir, "reset")+" && test -f "+filepath.Join(dir, "reset")), IsNil)
+	c.Assert(bc.Reset("test -f "+filepath.Join(dir, "missing")), NotNil)
+	// nothing is added before the first run
+	c.Assert(bc.Reset(""), IsNil)
+}
+
+func (s *testClusterSuite) TestSetGenerator(c *C) {
+	benches := bench.NewBenches(bench.NewCluster())
+	for _, name := range []string{"ycsb", "sysbench", "restore", "empty"} {
+		c.Assert(benches.SetGenerator("scale-out", name), IsNil)
+	}
+	c.Assert(benches.SetGenerator("scale-out", "tpch"), NotNil)
+	c.Assert(benches.SetGenerator("no-such-case", "ycsb"), NotNil)
+
+	// an unknown mix fails before the data is loaded or the bench runs
+	os.Setenv("SYSBENCH_MIX", "write-only")
+	defer os.Unsetenv("SYSBENCH_MIX")
+	c.Assert(benches.SetGenerator("scale-out", "sysbench"), IsNil)
+	bc := benches.GetBench("scale-out")
+	c.Assert(bc.Generate(), ErrorMatches, "unknown SYSBENCH_MIX write-only.*")
+	c.Assert(bc.Run(), ErrorMatches, "unknown SYSBENCH_MIX write-only.*")
+}
+
+func (s *testClusterSuite) TestSweepRestore(c *C) {
+	cluster := bench.NewCluster()
+	cluster.SetPDAddr(mockPDAddr)
+	c.Assert(cluster.WaitPDReady(1), IsNil)
+	c.Assert(cluster.SetPDConfig("region-schedule-limit", 4), IsNil)
+	params, err := utils.ParseSweep("region-schedule-limit=8,16")
+	c.Assert(err, IsNil)
+	bc := bench.NewBenches(cluster).GetBench("scale-out")
+	// the config is restored even if the sweep fails
+	err = cluster.Sweep(bc, params, func(reset bool, apply func() error) error {
+		c.Assert(apply(), IsNil)
+		c.Assert(pd.pdConfigValue("region-schedule-limit"), Equals, 8.0)
+		return fmt.Errorf("stop")
+	})
+	c.Assert(err, ErrorMatches, "stop")
+	c.Assert(pd.pdConfigValue("region-schedule-limit"), Equals, 4.0)
+}
+
+func (s *testClusterSuite) TestPDControl(c *C) {
+	cluster := bench.NewCluster()
+	cluster.SetPDAddr(mockPDAddr)
+
+	// PD is ready after retries
+	c.Assert(cluster.WaitPDReady(1), IsNil)
+	pd.takeCalls()
+	c.Assert(cluster.SetAllStoreLimit(2000), IsNil)
+	c.Assert(cluster.SetStoreLimit(1, 100), IsNil)
+	c.Assert(pd.takeCalls(), DeepEquals, []string{
+		`POST /pd/api/v1/stores/limit {"rate":2000}`,
+		`POST /pd/api/v1/store/1/limit {"rate":100}`,
+	})
+
+	c.Assert(cluster.AddScheduler("evict-leader-scheduler", map[string]interface{}{"store_id": 1}), IsNil)
+	c.Assert(cluster.RemoveScheduler("evict-leader-scheduler"), IsNil)
+	// a bad request is not retried
+	start := time.Now()
+	c.Assert(cluster.RemoveScheduler("evict-leader-scheduler"), NotNil)
+	c.Assert(time.Since(start) < time.Second, IsTrue)
+
+	c.Assert(cluster.SetPDConfig("leader-schedule-limit", 8), IsNil)
+
+	c.Assert(cluster.SetStoreLabels(1, map[string]string{"zone": "z1"}), IsNil)
+	c.Assert(cluster.SetStoreLabels(2, map[string]string{"zone": "z1"}), NotNil)
+	c.Assert(cluster.DeleteStore(1), IsNil)
+	c.Assert(cluster.DeleteStore(2), NotNil)
+	c.Assert(cluster.RemoveTombstone(), IsNil)
+
+	// only the given instances are scaled in, and the last one is never scaled in
+	c.Assert(cluster.ScaleIn("pd", []string{"127.0.0.3:2379"}), ErrorMatches, "pd 127.0.0.3:2379 is not found")
+	c.Assert(cluster.ScaleIn("pd", []string{"127.0.0.1:2379", "127.0.0.2:2379"}), ErrorMatches, "cannot scale in 2 of 2 pd instances")
+	c.Assert(cluster.ScaleIn("tikv", []string{"127.0.0.1:20160"}), ErrorMatches, "stores of 127.0.0.1:20160 are not all up")
+	// the newest up stores are scaled in by count
+	c.Assert(cluster.ScaleInNewest("tikv", 2), ErrorMatches, "1 tikv instances can be scaled in, want 2")
+	c.Assert(cluster.ScaleInNewest("tikv", 1), ErrorMatches, "cannot scale in 1 of 1 stores")
+	c.Assert(cluster.ScaleInNewest("tiflash", 1), ErrorMatches, "unknown component tiflash.*")
+	_, err := cluster.ScaleOut("tiflash", 1)
+	c.Assert(err, ErrorMatches, "unknown component tiflash.*")
+}
@@ -5,6 +5,8 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -16,8 +18,17 @@ import (
 
 type handler struct {
 	r         *render.Render
-	reports   []bench.WorkloadReport
+	reports   map[string][]bench.WorkloadReport
 	resources []bench.ResourceRequestItem
+
+	sync.Mutex
+	// pdUnready is the number of PD requests which fail before PD is ready
+	pdUnready  int
+	schedulers []string
+	pdConfig   map[string]interface{}
+	// calls records the platform operations on instances, such as "restart 1 tikv 127.0.0.1:20160",
+	// and the store limit requests to PD, such as "POST /pd/api/v1/stores/limit {"rate":2000}"
+	calls []string
 }
 
 func newHandler() *handler {
@@ -25,6 +36,8 @@ func newHandler() *handler {
 		r: render.New(render.Options{
 			IndentJSON: true,
 		}),
+		reports:  make(map[string][]bench.WorkloadReport),
+		pdConfig: make(map[string]interface{}),
 	}
 }
 
@@ -39,13 +52,53 @@ func handleScaleOut(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// recordCall checks the cluster and component of a platform operation on an instance, and records it.
+func (h *handler) recordCall(w http.ResponseWriter, r *http.Request, op string) {
+	vars := mux.Vars(r)
+	if vars["cluster"] != mockClusterID {
+		h.r.JSON(w, http.StatusNotFound, "cluster not found")
+		return
+	}
+	switch vars["component"] {
+	case "tikv", "pd", "tidb":
+	default:
+		h.r.JSON(w, http.StatusBadRequest, "unknown component")
+		return
+	}
+	if vars["address"] == "" {
+		h.r.JSON(w, http.StatusBadRequest, "empty address")
+		return
+	}
+	h.Lock()
+	h.calls = append(h.calls, strings.Join([]string{op, vars["cluster"], vars["component"], vars["address"]}, " "))
+	h.Unlock()
+	h.r.JSON(w, http.StatusOK, "")
+}
+
+// takeCalls returns the recorded calls and clears them.
+func (h *handler) takeCalls() []string {
+	h.Lock()
+	defer h.Unlock()
+	calls := h.calls
+	h.calls = nil
+	return calls
+}
+
+func (h *handler) handleRestart(w http.ResponseWriter, r *http.Request) {
+	h.recordCall(w, r, "restart")
+}
+
+func (h *handler) handleScaleIn(w http.ResponseWriter, r *http.Request) {
+	h.recordCall(w, r, "scale_in")
+}
+
 func (h *handler) getResults(w http.ResponseWriter, r *http.Request) {
-	var err error
-	if len(h.reports) == 0 {
-		err = h.r.JSON(w, http.StatusOK, h.reports)
-	} else {
-		err = h.r.JSON(w, http.StatusOK, h.reports[:1])
+	// the oldest first, the bench sorts reports by itself
+	reports := h.reports[mux.Vars(r)["cluster"]]
+	if reports == nil {
+		reports = []bench.WorkloadReport{}
 	}
+	err := h.r.JSON(w, http.StatusOK, reports)
 	if err != nil {
 		log.Warn("getResults meets error", zap.Error(err))
 	}
@@ -57,7 +110,8 @@ func (h *handler) postResults(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		err = h.r.JSON(w, http.StatusBadGateway, err.Error())
 	} else {
-		h.reports = append(h.reports, report)
+		cluster := mux.Vars(r)["cluster"]
+		h.reports[cluster] = append(h.reports[cluster], report)
 		err = h.r.JSON(w, http.StatusOK, "")
 	}
 	if err != nil {
@@ -65,19 +119,195 @@ func (h *handler) postResults(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *handler) pdReady(w http.ResponseWriter) bool {
+	h.Lock()
+	defer h.Unlock()
+	if h.pdUnready > 0 {
+		h.pdUnready--
+		h.r.JSON(w, http.StatusServiceUnavailable, "pd is not ready")
+		return false
+	}
+	return true
+}
+
+func (h *handler) getStores(w http.ResponseWriter, r *http.Request) {
+	if !h.pdReady(w) {
+		return
+	}
+	stores := bench.StoresInfo{Count: 1, Stores: []*bench.StoreInfo{{Store: bench.StoreMeta{ID: 1, StateName: "Up"}}}}
+	h.r.JSON(w, http.StatusOK, stores)
+}
+
+func (h *handler) getMembers(w http.ResponseWriter, r *http.Request) {
+	leader := &bench.PDMember{Name: "pd-1", MemberID: 1, ClientUrls: []string{"http://127.0.0.1:2379"}}
+	members := bench.PDMembers{
+		Members: []*bench.PDMember{leader, {Name: "pd-2", MemberID: 2, ClientUrls: []string{"http://127.0.0.2:2379"}}},
+		Leader:  leader,
+	}
+	h.r.JSON(w, http.StatusOK, members)
+}
+
+func (h *handler) setStoreLimit(w http.ResponseWriter, r *http.Request) {
+	if !h.pdReady(w) {
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var input map[string]interface{}
+	if err := json.Unmarshal(body, &input); err != nil || input["rate"] == nil {
+		h.r.JSON(w, http.StatusBadRequest, "invalid rate")
+		return
+	}
+	h.Lock()
+	h.calls = append(h.calls, r.Method+" "+r.URL.Path+" "+string(body))
+	h.Unlock()
+	h.r.JSON(w, http.StatusOK, "")
+}
+
+func (h *handler) deleteStore(w http.ResponseWriter, r *http.Request) {
+	if mux.Vars(r)["id"] != "1" {
+		h.r.JSON(w, http.StatusNotFound, "store not found")
+		return
+	}
+	h.r.JSON(w, http.StatusOK, "")
+}
+
+func (h *handler) setStoreLabels(w http.ResponseWriter, r *http.Request) {
+	var labels map[string]string
+	if err := readJSON(r.Body, &labels); err != nil || len(labels) == 0 {
+		h.r.JSON(w, http.StatusBadRequest, "invalid labels")
+		return
+	}
+	if mux.Vars(r)["id"] != "1" {
+		h.r.JSON(w, http.StatusNotFound, "store not found")
+		return
+	}
+	h.r.JSON(w, http.StatusOK, "")
+}
+
+func (h *handler) removeTombstone(w http.ResponseWriter, r *http.Request) {
+	h.r.JSON(w, http.StatusOK, "")
+}
+
+func (h *handler) addScheduler(w http.ResponseWriter, r *http.Request) {
+	var input map[string]interface{}
+	if err := readJSON(r.Body, &input); err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.Lock()
+	h.schedulers = append(h.schedulers, input["name"].(string))
+	h.Unlock()
+	h.r.JSON(w, http.StatusOK, "")
+}
+
+func (h *handler) removeScheduler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	h.Lock()
+	defer h.Unlock()
+	for i, s := range h.schedulers {
+		if s == name {
+			h.schedulers = append(h.schedulers[:i], h.schedulers[i+1:]...)
+			h.r.JSON(w, http.StatusOK, "")
+			return
+		}
+	}
+	h.r.JSON(w, http.StatusNotFound, "scheduler not found")
+}
+
+func (h *handler) getSchedulers(w http.ResponseWriter, r *http.Request) {
+	h.Lock()
+	defer h.Unlock()
+	h.r.JSON(w, http.StatusOK, h.schedulers)
+}
+
+func (h *handler) setPDConfig(w http.ResponseWriter, r *http.Request) {
+	var input map[string]interface{}
+	if err := readJSON(r.Body, &input); err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.Lock()
+	for k, v := range input {
+		h.pdConfig[k] = v
+	}
+	h.Unlock()
+	h.r.JSON(w, http.StatusOK, "")
+}
+
+func (h *handler) pdConfigValue(key string) interface{} {
+	h.Lock()
+	defer h.Unlock()
+	return h.pdConfig[key]
+}
+
+func (h *handler) getPDConfig(w http.ResponseWriter, r *http.Request) {
+	h.Lock()
+	defer h.Unlock()
+	h.r.JSON(w, http.StatusOK, h.pdConfig)
+}
+
 const (
 	mockServerAddr = "127.0.0.1:21212"
+	mockPDAddr     = "127.0.0.1:21213"
+	mockClusterID  = "1"
 )
 
+// platform is the handler of the mock server, tests check the calls recorded by it.
+var platform = newHandler()
+
+// pd is the handler of the mock PD server, tests check the config set to it.
+var pd = newHandler()
+
+// mockPDServer serves a part of PD API, the first two requests of stores fail as PD is not ready.
+func mockPDServer() {
+	r := mux.NewRouter().PathPrefix("/pd/api/v1").Subrouter()
+	h := pd
+	h.Lock()
+	h.pdUnready = 2
+	h.Unlock()
+
+	r.HandleFunc("/stores", h.getStores).Methods("GET")
+	r.HandleFunc("/stores/limit", h.setStoreLimit).Methods("POST")
+	r.HandleFunc("/store/{id}/limit", h.setStoreLimit).Methods("POST")
+	r.HandleFunc("/members", h.getMembers).Methods("GET")
+	r.HandleFunc("/stores/remove-tombstone", h.removeTombstone).Methods("DELETE")
+	r.HandleFunc("/store/{id}", h.deleteStore).Methods("DELETE")
+	r.HandleFunc("/store/{id}/label", h.setStoreLabels).Methods("POST")
+	r.HandleFunc("/schedulers", h.addScheduler).Methods("POST")
+	r.HandleFunc("/schedulers", h.getSchedulers).Methods("GET")
+	r.HandleFunc("/schedulers/{name}", h.removeScheduler).Methods("DELETE")
+	r.HandleFunc("/config", h.setPDConfig).Methods("POST")
+	r.HandleFunc("/config", h.getPDConfig).Methods("GET")
+
+	srv := &http.Server{
+		Addr:         mockPDAddr,
+		WriteTimeout: time.Second * 15,
+		ReadTimeout:  time.Second * 15,
+		IdleTimeout:  time.Second * 60,
+		Handler:      r,
+	}
+	if err := srv.ListenAndServe(); err != nil {
+		log.Error("start mock pd server meets error", zap.Error(err))
+	}
+}
+
 func mockServer() {
 	r := mux.NewRouter().PathPrefix("/api/cluster").Subrouter()
-	h := newHandler()
+	h := platform
 	resource := bench.ResourceRequestItem{}
 	resource.ID = 1
+	resource.InstanceType = "i3.2xlarge"
+	resource.Labels = "zone=z1,rack=r1,host=h1"
 	h.resources = append(h.resources, resource)
 
 	r.HandleFunc("/resource/{cluster}", h.handleResource).Methods("GET", "POST")
 	r.HandleFunc("/scale_out/{cluster}/{id}/{component}", handleScaleOut).Methods("POST")
+	r.HandleFunc("/restart/{cluster}/{component}/{address}", h.handleRestart).Methods("POST")
+	r.HandleFunc("/scale_in/{cluster}/{component}/{address}", h.handleScaleIn).Methods("POST")
 	r.HandleFunc("/workload/{cluster}/result", h.postResults).Methods("POST")
 	r.HandleFunc("/workload/{cluster}/result", h.getResults).Methods("GET")
 
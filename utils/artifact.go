@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// ArtifactBundle is the name of the packed artifacts, it is linked by the report.
+const ArtifactBundle = "workload.tar.gz"
+
+// Artifacts collects the files produced by a run into a directory.
+type Artifacts struct {
+	sync.Mutex
+	dir string
+}
+
+// NewArtifacts returns Artifacts which writes to dir.
+func NewArtifacts(dir string) *Artifacts {
+	return &Artifacts{dir: dir}
+}
+
+// Dir returns the artifact directory.
+func (a *Artifacts) Dir() string {
+	return a.dir
+}
+
+// Path returns the path of name in the artifact directory.
+func (a *Artifacts) Path(name string) string {
+	return filepath.Join(a.dir, name)
+}
+
+func (a *Artifacts) ensureDir(name string) error {
+	return os.MkdirAll(filepath.Dir(a.Path(name)), 0755)
+}
+
+// Prepare creates the parent directory of name and returns its path, it is used by writers outside Artifacts.
+func (a *Artifacts) Prepare(name string) (string, error) {
+	a.Lock()
+	defer a.Unlock()
+	return a.Path(name), a.ensureDir(name)
+}
+
+// WriteFile writes data to name in the artifact directory.
+func (a *Artifacts) WriteFile(name string, data []byte) error {
+	a.Lock()
+	defer a.Unlock()
+	if err := a.ensureDir(name); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(a.Path(name), data, 0644)
+}
+
+// AppendFile appends data to name in the artifact directory.
+func (a *Artifacts) AppendFile(name string, data []byte) error {
+	a.Lock()
+	defer a.Unlock()
+	if err := a.ensureDir(name); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(a.Path(name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(data)
+	return err
+}
+
+// WriteJSON writes v as indented json to name in the artifact directory.
+func (a *Artifacts) WriteJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return a.WriteFile(name, data)
+}
+
+// CopyFile copies src to name in the artifact directory, it is a no-op if src is already there.
+func (a *Artifacts) CopyFile(name, src string) error {
+	a.Lock()
+	defer a.Unlock()
+	dst := a.Path(name)
+	srcAbs, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+	dstAbs, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	if srcAbs == dstAbs {
+		return nil
+	}
+	if err := a.ensureDir(name); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// Pack packs all files in the artifact directory into ArtifactBundle.
+func (a *Artifacts) Pack() error {
+	a.Lock()
+	defer a.Unlock()
+	if err := os.MkdirAll(a.dir, 0755); err != nil {
+		return err
+	}
+	bundle := a.Path(ArtifactBundle)
+	tmp, err := ioutil.TempFile(a.dir, ArtifactBundle+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	gw := gzip.NewWriter(tmp)
+	tw := tar.NewWriter(gw)
+	err = filepath.Walk(a.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || path == bundle || path == tmp.Name() {
+			return nil
+		}
+		rel, err := filepath.Rel(a.dir, path)
+		if err != nil {
+			return err
+		}
+		return addToTar(tw, path, rel, info)
+	})
+	if err != nil {
+		tmp.Close()
+		return errors.Annotate(err, "pack artifacts")
+	}
+	if err = tw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = gw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	log.Info("pack artifacts", zap.String("bundle", bundle))
+	return os.Rename(tmp.Name(), bundle)
+}
+
+func addToTar(tw *tar.Writer, path, name string, info os.FileInfo) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(name)
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	. "github.com/pingcap/check"
+)
+
+type testArtifactSuite struct{}
+
+var _ = Suite(&testArtifactSuite{})
+
+func (s *testArtifactSuite) TestPack(c *C) {
+	dir := c.MkDir()
+	a := NewArtifacts(dir)
+	c.Assert(a.WriteFile("report.txt", []byte("report")), IsNil)
+	c.Assert(a.WriteJSON("run.json", map[string]string{"case": "scale-out"}), IsNil)
+	c.Assert(a.AppendFile("logs/cmd.log", []byte("a")), IsNil)
+	c.Assert(a.AppendFile("logs/cmd.log", []byte("b")), IsNil)
+	src := filepath.Join(c.MkDir(), "simLog")
+	c.Assert(ioutil.WriteFile(src, []byte("sim"), 0644), IsNil)
+	c.Assert(a.CopyFile("simLog", src), IsNil)
+
+	c.Assert(a.Pack(), IsNil)
+	// pack again should not include the bundle itself
+	c.Assert(a.Pack(), IsNil)
+
+	f, err := os.Open(a.Path(ArtifactBundle))
+	c.Assert(err, IsNil)
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	c.Assert(err, IsNil)
+	tr := tar.NewReader(gr)
+	var names []string
+	contents := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+		data, err := ioutil.ReadAll(tr)
+		c.Assert(err, IsNil)
+		contents[header.Name] = string(data)
+	}
+	sort.Strings(names)
+	c.Assert(names, DeepEquals, []string{"logs/cmd.log", "report.txt", "run.json", "simLog"})
+	c.Assert(contents["logs/cmd.log"], Equals, "ab")
+	c.Assert(contents["simLog"], Equals, "sim")
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// StoreUp is the state name of a store which serves.
+	StoreUp = "Up"
+	// StoreTombstone is the state name of a store whose regions are all moved away after it is deleted.
+	StoreTombstone = "Tombstone"
+)
+
+// DefaultBalanceTolerance is the spread of scores, relative to the mean, under which stores are balanced.
+const DefaultBalanceTolerance = 0.05
+
+// StoreStats is the stats of a store reported by PD.
+type StoreStats struct {
+	ID          uint64  `json:"ID"`
+	Address     string  `json:"Address"`
+	State       string  `json:"State"`
+	LeaderCount int     `json:"LeaderCount"`
+	RegionCount int     `json:"RegionCount"`
+	LeaderScore float64 `json:"LeaderScore"`
+	RegionScore float64 `json:"RegionScore"`
+	// Capacity and Available are in bytes, RegionSize is in MiB.
+	Capacity     uint64            `json:"Capacity,omitempty"`
+	Available    uint64            `json:"Available,omitempty"`
+	RegionSize   int64             `json:"RegionSize,omitempty"`
+	RegionWeight float64           `json:"RegionWeight,omitempty"`
+	Labels       map[string]string `json:"Labels,omitempty"`
+}
+
+// balanceOperators are the descriptions of operators which are created by balance-leader and balance-region schedulers.
+var balanceOperators = map[string]struct{}{
+	"balance-leader": {},
+	"balance-region": {},
+}
+
+// OperatorDesc returns the description of an operator from the operators API of PD, such as balance-leader.
+// PD reports an operator as a string which starts with its description, such as "balance-leader {transfer leader: ...} (kind:...)".
+func OperatorDesc(raw json.RawMessage) string {
+	var operator string
+	if err := json.Unmarshal(raw, &operator); err != nil {
+		return ""
+	}
+	return strings.SplitN(strings.TrimSpace(operator), " ", 2)[0]
+}
+
+// IsBalanceOperator returns true if the operator is created by balance-leader or balance-region scheduler.
+func IsBalanceOperator(raw json.RawMessage) bool {
+	_, ok := balanceOperators[OperatorDesc(raw)]
+	return ok
+}
+
+// Spread returns (max - min) / mean of values, it is 0 if there are less than two values.
+func Spread(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	min, max, sum := math.Inf(1), math.Inf(-1), 0.0
+	for _, v := range values {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if mean == 0 {
+		return 0
+	}
+	return (max - min) / mean
+}
+
+// IsStoreBalanced returns true if both leader and region scores of up stores spread within tolerance.
+func IsStoreBalanced(stores []StoreStats, tolerance float64) bool {
+	var leaders, regions []float64
+	for _, s := range stores {
+		if s.State != StoreUp {
+			continue
+		}
+		leaders = append(leaders, s.LeaderScore)
+		regions = append(regions, s.RegionScore)
+	}
+	return Spread(leaders) <= tolerance && Spread(regions) <= tolerance
+}
+
+// CapacityStore is the stats of a store which are relative to its capacity.
+type CapacityStore struct {
+	// CapacityGiB is the capacity of the store in GiB.
+	CapacityGiB float64 `json:"CapacityGiB" bench:"unit=GiB"`
+	// UsedRatio is the used part of the capacity.
+	UsedRatio    float64 `json:"UsedRatio"`
+	RegionWeight float64 `json:"RegionWeight"`
+	RegionScore  float64 `json:"RegionScore"`
+	// NormalizedScore is the region score per GiB of capacity.
+	NormalizedScore float64 `json:"NormalizedScore"`
+}
+
+// CapacityStores returns the stats of up stores relative to their capacity by store id,
+// stores without capacity are skipped.
+func CapacityStores(stores []StoreStats) map[string]CapacityStore {
+	ret := make(map[string]CapacityStore)
+	for _, s := range stores {
+		if s.State != StoreUp || s.Capacity == 0 {
+			continue
+		}
+		capacity := float64(s.Capacity) / (1 << 30)
+		ret[strconv.FormatUint(s.ID, 10)] = CapacityStore{
+			CapacityGiB:     capacity,
+			UsedRatio:       float64(s.Capacity-s.Available) / float64(s.Capacity),
+			RegionWeight:    s.RegionWeight,
+			RegionScore:     s.RegionScore,
+			NormalizedScore: s.RegionScore / capacity,
+		}
+	}
+	return ret
+}
+
+// UsedRatioSpread returns the spread of used ratios of up stores with capacity,
+// it is small if stores are balanced according to their capacity.
+func UsedRatioSpread(stores []StoreStats) float64 {
+	var used []float64
+	for _, s := range CapacityStores(stores) {
+		used = append(used, s.UsedRatio)
+	}
+	return Spread(used)
+}
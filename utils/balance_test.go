@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"encoding/json"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testBalanceSuite{})
+
+type testBalanceSuite struct{}
+
+func (s *testBalanceSuite) TestSpread(c *C) {
+	c.Assert(Spread(nil), Equals, 0.0)
+	c.Assert(Spread([]float64{10}), Equals, 0.0)
+	c.Assert(Spread([]float64{0, 0}), Equals, 0.0)
+	c.Assert(Spread([]float64{90, 100, 110}), Equals, 0.2)
+}
+
+func (s *testBalanceSuite) TestIsStoreBalanced(c *C) {
+	stores := []StoreStats{
+		{ID: 1, State: StoreUp, LeaderScore: 100, RegionScore: 1000},
+		{ID: 2, State: StoreUp, LeaderScore: 102, RegionScore: 1010},
+		{ID: 3, State: "Tombstone"},
+	}
+	c.Assert(IsStoreBalanced(stores, DefaultBalanceTolerance), IsTrue)
+	// a new store has no region yet
+	stores = append(stores, StoreStats{ID: 4, State: StoreUp})
+	c.Assert(IsStoreBalanced(stores, DefaultBalanceTolerance), IsFalse)
+	stores[3].LeaderScore, stores[3].RegionScore = 101, 1005
+	c.Assert(IsStoreBalanced(stores, DefaultBalanceTolerance), IsTrue)
+	stores[3].RegionScore = 800
+	c.Assert(IsStoreBalanced(stores, DefaultBalanceTolerance), IsFalse)
+}
+
+func (s *testBalanceSuite) TestCapacityStores(c *C) {
+	stores := []StoreStats{
+		{ID: 1, State: StoreUp, RegionScore: 1000, Capacity: 100 << 30, Available: 75 << 30, RegionWeight: 1},
+		{ID: 2, State: StoreUp, RegionScore: 2000, Capacity: 200 << 30, Available: 150 << 30, RegionWeight: 2},
+		{ID: 3, State: StoreUp},
+		{ID: 4, State: StoreTombstone, Capacity: 100 << 30},
+	}
+	ret := CapacityStores(stores)
+	c.Assert(ret, HasLen, 2)
+	c.Assert(ret["1"], DeepEquals, CapacityStore{CapacityGiB: 100, UsedRatio: 0.25, RegionWeight: 1, RegionScore: 1000, NormalizedScore: 10})
+	c.Assert(ret["2"].NormalizedScore, Equals, 10.0)
+}
+
+func (s *testBalanceSuite) TestUsedRatioSpread(c *C) {
+	// equal region scores on stores of different capacity are not balanced according to capacity
+	stores := []StoreStats{
+		{ID: 1, State: StoreUp, RegionScore: 1000, Capacity: 100 << 30, Available: 50 << 30},
+		{ID: 2, State: StoreUp, RegionScore: 1000, Capacity: 200 << 30, Available: 150 << 30},
+	}
+	c.Assert(almostEqual(UsedRatioSpread(stores), 2.0/3, 1e-9), IsTrue)
+	stores[1].Available = 100 << 30
+	c.Assert(UsedRatioSpread(stores), Equals, 0.0)
+	// stores without capacity are skipped
+	stores = append(stores, StoreStats{ID: 3, State: StoreUp})
+	c.Assert(UsedRatioSpread(stores), Equals, 0.0)
+}
+
+func (s *testBalanceSuite) TestIsBalanceOperator(c *C) {
+	c.Assert(IsBalanceOperator(json.RawMessage(`"balance-leader {transfer leader: store 1 to 2} (kind:leader, region:2(1,1))"`)), IsTrue)
+	c.Assert(IsBalanceOperator(json.RawMessage(`"balance-region {mv peer: store [1] to [4]} (kind:region)"`)), IsTrue)
+	c.Assert(IsBalanceOperator(json.RawMessage(`"transfer-hot-read-leader {transfer leader: store 1 to 2} (kind:hot-region,leader)"`)), IsFalse)
+	c.Assert(IsBalanceOperator(json.RawMessage(`"merge-region {merge: region 2 to 3} (kind:merge)"`)), IsFalse)
+	c.Assert(IsBalanceOperator(json.RawMessage(`{"desc": "balance-leader"}`)), IsFalse)
+}
@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"os"
+
+	"github.com/go-echarts/go-echarts/charts"
+)
+
+const (
+	regressedColor = "#d94e5d"
+	improvedColor  = "#50a3ba"
+	lastColor      = "#c0c0c0"
+	curColor       = "#5470c6"
+)
+
+// compareTooltip shows raw values and percentage delta of a metric.
+var compareTooltip = charts.FuncOpts(`function (params) {
+	var d = params[params.length - 1].data;
+	return params[0].name + '<br/>last: ' + d.last + ' ' + d.unit + '<br/>cur: ' + d.cur + ' ' + d.unit +
+		'<br/>delta: ' + d.delta + ' ' + d.verdict;
+}`)
+
+// chartItem is a bar in the chart, fields other than value and itemStyle are used by the tooltip.
+type chartItem struct {
+	Value     float64               `json:"value"`
+	Last      float64               `json:"last"`
+	Cur       float64               `json:"cur"`
+	Delta     string                `json:"delta"`
+	Unit      string                `json:"unit"`
+	Verdict   Verdict               `json:"verdict"`
+	ItemStyle *charts.ItemStyleOpts `json:"itemStyle,omitempty"`
+}
+
+// categoryPairs groups pairs by category in the order of first appearance.
+func categoryPairs(pairs []MetricPair) ([]string, map[string][]MetricPair) {
+	var categories []string
+	groups := make(map[string][]MetricPair)
+	for _, p := range pairs {
+		if _, ok := groups[p.Category]; !ok {
+			categories = append(categories, p.Category)
+		}
+		groups[p.Category] = append(groups[p.Category], p)
+	}
+	return categories, groups
+}
+
+// commonUnit returns the unit if all pairs share it.
+func commonUnit(pairs []MetricPair) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	for _, p := range pairs[1:] {
+		if p.Unit != pairs[0].Unit {
+			return ""
+		}
+	}
+	return pairs[0].Unit
+}
+
+func metricLabel(name, unit string) string {
+	if unit == "" {
+		return name
+	}
+	return name + " (" + unit + ")"
+}
+
+func (s *Stats) categoryBar(category string, pairs []MetricPair) *charts.Bar {
+	var xAxis []string
+	var lastData, curData []chartItem
+	unit := commonUnit(pairs)
+	for _, p := range pairs {
+		label := p.Name
+		if unit == "" {
+			label = metricLabel(p.Name, p.Unit)
+		}
+		xAxis = append(xAxis, label)
+		last := p.Last
+		d := NewMetricDiff(p.Category, p.Name, &last, p.Cur, p.Better, s.threshold(), true)
+		item := chartItem{Last: p.Last, Cur: p.Cur, Delta: d.FormatDelta(), Unit: p.Unit, Verdict: d.Verdict}
+		lastItem := item
+		lastItem.Value = p.Last
+		lastData = append(lastData, lastItem)
+		curItem := item
+		curItem.Value = p.Cur
+		switch d.Verdict {
+		case VerdictRegressed:
+			curItem.ItemStyle = &charts.ItemStyleOpts{Color: regressedColor}
+		case VerdictImproved:
+			curItem.ItemStyle = &charts.ItemStyleOpts{Color: improvedColor}
+		}
+		curData = append(curData, curItem)
+	}
+	bar := charts.NewBar()
+	bar.SetGlobalOptions(
+		charts.TitleOpts{Title: categoryTitle(s.title(), category), Subtitle: "red is regressed, blue is improved"},
+		charts.ToolboxOpts{Show: true},
+		charts.TooltipOpts{Show: true, Trigger: "axis", Formatter: compareTooltip},
+		charts.YAxisOpts{Name: unit},
+	)
+	bar.AddXAxis(xAxis).
+		AddYAxis("last", lastData, charts.ItemStyleOpts{Color: lastColor}).
+		AddYAxis("cur", curData, charts.ItemStyleOpts{Color: curColor})
+	return bar
+}
+
+// RenderTo visualization, there is a bar chart of raw values for each category.
+func (s *Stats) RenderTo(fileName string) error {
+	page := charts.NewPage()
+	categories, groups := categoryPairs(s.pairs)
+	for _, category := range categories {
+		page.Add(s.categoryBar(category, groups[category]))
+	}
+	return renderPage(fileName, page)
+}
+
+// TrendPoint is a labeled report, such as a report in the history or a combination of a sweep.
+type TrendPoint struct {
+	// Label is shown on the x axis, such as the time or version of the report.
+	Label   string
+	Metrics []Metric
+}
+
+// pointSeries is the metrics of points grouped by category.
+type pointSeries struct {
+	xAxis      []string
+	categories []string
+	names      map[string][]string
+	units      map[string]string
+	values     map[string][]interface{}
+}
+
+func newPointSeries(points []TrendPoint) *pointSeries {
+	s := &pointSeries{
+		names:  make(map[string][]string),
+		units:  make(map[string]string),
+		values: make(map[string][]interface{}),
+	}
+	for i, point := range points {
+		s.xAxis = append(s.xAxis, point.Label)
+		for _, m := range point.Metrics {
+			if _, ok := s.values[m.Name]; !ok {
+				if _, ok := s.names[m.Category]; !ok {
+					s.categories = append(s.categories, m.Category)
+				}
+				s.names[m.Category] = append(s.names[m.Category], m.Name)
+				s.units[m.Name] = m.Unit
+				// a metric which is missing in the point is shown as a gap
+				s.values[m.Name] = make([]interface{}, len(points))
+				for j := range s.values[m.Name] {
+					s.values[m.Name][j] = "-"
+				}
+			}
+			s.values[m.Name][i] = m.Value
+		}
+	}
+	return s
+}
+
+func categoryTitle(title, category string) string {
+	if category == "" {
+		category = "other"
+	}
+	return title + ": " + category
+}
+
+func renderPage(fileName string, page *charts.Page) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return page.Render(f)
+}
+
+// RenderTrend renders the history of reports as trend lines, there is a line chart for each category.
+func RenderTrend(fileName, title string, points []TrendPoint) error {
+	series := newPointSeries(points)
+	page := charts.NewPage()
+	for _, category := range series.categories {
+		line := charts.NewLine()
+		line.SetGlobalOptions(
+			charts.TitleOpts{Title: categoryTitle(title, category)},
+			charts.ToolboxOpts{Show: true},
+			charts.TooltipOpts{Show: true, Trigger: "axis"},
+			charts.DataZoomOpts{Type: "slider"},
+		)
+		line.AddXAxis(series.xAxis)
+		for _, metric := range series.names[category] {
+			line.AddYAxis(metricLabel(metric, series.units[metric]), series.values[metric])
+		}
+		page.Add(line)
+	}
+	return renderPage(fileName, page)
+}
+
+// RenderSweep renders the results of sweep combinations, there is a bar chart for each category.
+func RenderSweep(fileName, title string, points []TrendPoint) error {
+	series := newPointSeries(points)
+	page := charts.NewPage()
+	for _, category := range series.categories {
+		bar := charts.NewBar()
+		bar.SetGlobalOptions(
+			charts.TitleOpts{Title: categoryTitle(title, category)},
+			charts.ToolboxOpts{Show: true},
+			charts.TooltipOpts{Show: true, Trigger: "axis"},
+		)
+		bar.AddXAxis(series.xAxis)
+		for _, metric := range series.names[category] {
+			bar.AddYAxis(metricLabel(metric, series.units[metric]), series.values[metric])
+		}
+		page.Add(bar)
+	}
+	return renderPage(fileName, page)
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+type testChartSuite struct{}
+
+var _ = Suite(&testChartSuite{})
+
+func (s *testChartSuite) TestRenderTrend(c *C) {
+	points := []TrendPoint{
+		{Label: "v4.0.7", Metrics: []Metric{{Name: "balance_time", Category: "balance", Unit: "s", Value: 100}}},
+		{Label: "v4.0.8", Metrics: []Metric{
+			{Name: "balance_time", Category: "balance", Unit: "s", Value: 90},
+			{Name: "cur_query_latency", Category: "latency", Unit: "s", Value: 0.01},
+		}},
+	}
+	fileName := filepath.Join(c.MkDir(), "trend.html")
+	c.Assert(RenderTrend(fileName, "scale-out", points), IsNil)
+	data, err := ioutil.ReadFile(fileName)
+	c.Assert(err, IsNil)
+	html := string(data)
+	c.Assert(strings.Contains(html, "balance_time (s)"), IsTrue)
+	c.Assert(strings.Contains(html, "scale-out: latency"), IsTrue)
+	// latency is missing in the first point
+	c.Assert(strings.Contains(html, `["-",0.01]`), IsTrue)
+
+	c.Assert(RenderTrend(filepath.Join(c.MkDir(), "no", "such", "dir.html"), "scale-out", points), NotNil)
+}
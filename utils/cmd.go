@@ -2,31 +2,93 @@ package utils
 
 import (
 	"bytes"
+	"fmt"
+	"os"
 	"os/exec"
+	"strings"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"go.uber.org/zap"
 )
 
 // Command directly run command
 type Command struct {
-	path string
-	args []string
+	path    string
+	args    []string
+	logFile string
+
+	cmd    *exec.Cmd
+	stdout bytes.Buffer
+	stderr bytes.Buffer
 }
 
-// NewCommand returns Command
+// NewCommand returns Command, args are the argv of the program, so that the first of them is the command name.
 func NewCommand(path string, args ...string) *Command {
 	return &Command{path: path, args: args}
 }
 
+// SetLogFile makes the command append its output to the file.
+func (command *Command) SetLogFile(path string) *Command {
+	command.logFile = path
+	return command
+}
+
 // Run run command and return result
 func (command *Command) Run() (string, error) {
-	var stdout, stderr bytes.Buffer
-	cmd := exec.Cmd{Path: command.path, Args: command.args}
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
-	log.Info(cmd.Path, zap.Strings("cmd", cmd.Args),
-		zap.String("stdout", stdout.String()), zap.String("stderr", stderr.String()))
-	return stdout.String(), err
+	if err := command.Start(); err != nil {
+		return "", err
+	}
+	return command.Wait()
+}
+
+// Start starts the command without waiting for it.
+func (command *Command) Start() error {
+	command.stdout.Reset()
+	command.stderr.Reset()
+	command.cmd = &exec.Cmd{Path: command.path, Args: command.args, Stdout: &command.stdout, Stderr: &command.stderr}
+	err := command.cmd.Start()
+	if err != nil && command.logFile != "" {
+		command.writeLog("", "", err)
+	}
+	return err
+}
+
+// Wait waits for the started command and returns result.
+func (command *Command) Wait() (string, error) {
+	if command.cmd == nil {
+		return "", errors.New("command is not started")
+	}
+	err := command.cmd.Wait()
+	stdout, stderr := command.stdout.String(), command.stderr.String()
+	log.Info(command.cmd.Path, zap.Strings("cmd", command.cmd.Args),
+		zap.String("stdout", stdout), zap.String("stderr", stderr))
+	if command.logFile != "" {
+		command.writeLog(stdout, stderr, err)
+	}
+	return stdout, err
+}
+
+// Kill kills the started command, Wait should still be called to release it.
+func (command *Command) Kill() error {
+	if command.cmd == nil || command.cmd.Process == nil {
+		return nil
+	}
+	return command.cmd.Process.Kill()
+}
+
+func (command *Command) writeLog(stdout, stderr string, runErr error) {
+	f, err := os.OpenFile(command.logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Warn("failed to open command log", zap.String("file", command.logFile), zap.Error(err))
+		return
+	}
+	defer f.Close()
+	text := fmt.Sprintf("$ %s %s\n--- stdout\n%s\n--- stderr\n%s\n", command.path, strings.Join(command.args, " "), stdout, stderr)
+	if runErr != nil {
+		text += fmt.Sprintf("--- error\n%v\n", runErr)
+	}
+	if _, err = f.WriteString(text); err != nil {
+		log.Warn("failed to write command log", zap.String("file", command.logFile), zap.Error(err))
+	}
 }
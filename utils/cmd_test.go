@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testCmdSuite{})
+
+type testCmdSuite struct{}
+
+func (s *testCmdSuite) TestCommand(c *C) {
+	logFile := filepath.Join(c.MkDir(), "echo.log")
+	out, err := NewCommand("/bin/echo", "echo", "load", "mysql").SetLogFile(logFile).Run()
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "load mysql\n")
+	data, err := ioutil.ReadFile(logFile)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(data), "$ /bin/echo echo load mysql"), IsTrue)
+
+	// the first of args is argv[0], so that the next is $1 of a script
+	script := filepath.Join(c.MkDir(), "args.sh")
+	c.Assert(ioutil.WriteFile(script, []byte("#!/bin/sh\necho \"$0 $1 $#\"\n"), 0755), IsNil)
+	out, err = NewCommand(script, "args.sh", "first", "second").Run()
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, script+" first 2\n")
+
+	cmd := NewCommand("/bin/sleep", "sleep", "10")
+	c.Assert(cmd.Start(), IsNil)
+	c.Assert(cmd.Kill(), IsNil)
+	_, err = cmd.Wait()
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TableSnapshot is the content of a table recorded after Generate, which is compared after Run.
+type TableSnapshot struct {
+	Table    string
+	Rows     int64
+	Checksum uint64
+	Columns  []string
+	// Samples are rows which should be read back unchanged.
+	Samples [][]interface{}
+}
+
+// Diverge returns the differences between the snapshot and the current one of the same table.
+func (s *TableSnapshot) Diverge(cur *TableSnapshot) []string {
+	var ret []string
+	if s.Rows != cur.Rows {
+		ret = append(ret, fmt.Sprintf("%s: rows %d != %d", s.Table, cur.Rows, s.Rows))
+	}
+	if s.Checksum != cur.Checksum {
+		ret = append(ret, fmt.Sprintf("%s: checksum %d != %d", s.Table, cur.Checksum, s.Checksum))
+	}
+	return ret
+}
+
+// SampleQuery returns the query to count the rows which are equal to a sample, NULL is matched too.
+func (s *TableSnapshot) SampleQuery() string {
+	conds := make([]string, 0, len(s.Columns))
+	for _, column := range s.Columns {
+		conds = append(conds, fmt.Sprintf("`%s` <=> ?", column))
+	}
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", s.Table, strings.Join(conds, " AND "))
+}
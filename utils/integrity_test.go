@@ -0,0 +1,21 @@
+package utils
+
+import (
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testIntegritySuite{})
+
+type testIntegritySuite struct{}
+
+func (s *testIntegritySuite) TestDiverge(c *C) {
+	last := &TableSnapshot{Table: "test.test_go_ycsb", Rows: 1000, Checksum: 42}
+	c.Assert(last.Diverge(&TableSnapshot{Table: "test.test_go_ycsb", Rows: 1000, Checksum: 42}), HasLen, 0)
+	c.Assert(last.Diverge(&TableSnapshot{Table: "test.test_go_ycsb", Rows: 999, Checksum: 7}), DeepEquals,
+		[]string{"test.test_go_ycsb: rows 999 != 1000", "test.test_go_ycsb: checksum 7 != 42"})
+}
+
+func (s *testIntegritySuite) TestSampleQuery(c *C) {
+	snapshot := &TableSnapshot{Table: "sbtest.sbtest1", Columns: []string{"id", "k"}}
+	c.Assert(snapshot.SampleQuery(), Equals, "SELECT COUNT(*) FROM sbtest.sbtest1 WHERE `id` <=> ? AND `k` <=> ?")
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// GitHash is the revision of bench, it is set by ldflags.
+var GitHash = "None"
+
+// HostInfo is the machine which runs bench.
+type HostInfo struct {
+	Hostname  string `json:"hostname"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	CPUs      int    `json:"cpus"`
+	GoVersion string `json:"go_version"`
+}
+
+// NewHostInfo returns the info of current host.
+func NewHostInfo() HostInfo {
+	hostname, _ := os.Hostname()
+	return HostInfo{
+		Hostname:  hostname,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		GoVersion: runtime.Version(),
+	}
+}
+
+// Metadata describes the run and environment which produce a report.
+type Metadata struct {
+	Case         string            `json:"case"`
+	Config       map[string]string `json:"config,omitempty"`
+	Versions     map[string]string `json:"versions,omitempty"`
+	Topology     map[string]int    `json:"topology,omitempty"`
+	BenchVersion string            `json:"bench_version"`
+	StartTime    time.Time         `json:"start_time"`
+	EndTime      time.Time         `json:"end_time"`
+	Host         HostInfo          `json:"host"`
+	Error        string            `json:"error,omitempty"`
+	// MetricWarnings is the count of warnings returned by prometheus, the metrics may be partial if it is not 0.
+	// The warnings of each query are in the metric dump of artifacts.
+	MetricWarnings int `json:"metric_warnings,omitempty"`
+}
+
+// NewMetadata returns Metadata of a run which starts now.
+func NewMetadata(caseName string) *Metadata {
+	return &Metadata{
+		Case:         caseName,
+		Config:       make(map[string]string),
+		Versions:     make(map[string]string),
+		Topology:     make(map[string]int),
+		BenchVersion: GitHash,
+		StartTime:    time.Now(),
+		Host:         NewHostInfo(),
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Header returns the plaintext header of metadata.
+func (m *Metadata) Header() string {
+	if m == nil {
+		return ""
+	}
+	text := "metadata:  \n"
+	text += fmt.Sprintf("\t* case: %s  \n", m.Case)
+	text += fmt.Sprintf("\t* bench: %s  \n", m.BenchVersion)
+	for _, k := range sortedKeys(m.Versions) {
+		text += fmt.Sprintf("\t* %s version: %s  \n", k, m.Versions[k])
+	}
+	components := make([]string, 0, len(m.Topology))
+	for k := range m.Topology {
+		components = append(components, k)
+	}
+	sort.Strings(components)
+	for _, k := range components {
+		text += fmt.Sprintf("\t* %s num: %d  \n", k, m.Topology[k])
+	}
+	for _, k := range sortedKeys(m.Config) {
+		text += fmt.Sprintf("\t* %s: %s  \n", k, m.Config[k])
+	}
+	text += fmt.Sprintf("\t* time: %s ~ %s  \n", m.StartTime.Format(time.RFC3339), m.EndTime.Format(time.RFC3339))
+	text += fmt.Sprintf("\t* host: %s %s/%s %d cpus  \n", m.Host.Hostname, m.Host.OS, m.Host.Arch, m.Host.CPUs)
+	if m.Error != "" {
+		text += fmt.Sprintf("\t* failed: %s  \n", m.Error)
+	}
+	if m.MetricWarnings > 0 {
+		text += fmt.Sprintf("\t* prometheus warnings: %d, metrics may be partial  \n", m.MetricWarnings)
+	}
+	return text
+}
+
+// ConfigDiff returns the differences of case config and topology between two runs.
+func (m *Metadata) ConfigDiff(other *Metadata) []string {
+	if m == nil || other == nil {
+		return nil
+	}
+	var diffs []string
+	if m.Case != other.Case {
+		diffs = append(diffs, fmt.Sprintf("case: %s != %s", m.Case, other.Case))
+	}
+	keys := make(map[string]string)
+	for k := range m.Config {
+		keys[k] = ""
+	}
+	for k := range other.Config {
+		keys[k] = ""
+	}
+	for _, k := range sortedKeys(keys) {
+		if m.Config[k] != other.Config[k] {
+			diffs = append(diffs, fmt.Sprintf("%s: %s != %s", k, m.Config[k], other.Config[k]))
+		}
+	}
+	keys = make(map[string]string)
+	for k := range m.Topology {
+		keys[k] = ""
+	}
+	for k := range other.Topology {
+		keys[k] = ""
+	}
+	for _, k := range sortedKeys(keys) {
+		if m.Topology[k] != other.Topology[k] {
+			diffs = append(diffs, fmt.Sprintf("%s num: %d != %d", k, m.Topology[k], other.Topology[k]))
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+type testMetaSuite struct{}
+
+var _ = Suite(&testMetaSuite{})
+
+func (s *testMetaSuite) TestConfigDiff(c *C) {
+	last := NewMetadata("scale-out")
+	last.Config["SCALE_NUM"] = "1"
+	last.Topology["tikv"] = 3
+	cur := NewMetadata("scale-out")
+	cur.Config["SCALE_NUM"] = "1"
+	cur.Topology["tikv"] = 3
+	cur.Versions["pd"] = "v4.0.8"
+	c.Assert(cur.ConfigDiff(last), HasLen, 0)
+
+	cur.Config["SCALE_NUM"] = "2"
+	cur.Config["STORE_LIMIT"] = "2000"
+	cur.Topology["tikv"] = 4
+	diffs := cur.ConfigDiff(last)
+	c.Assert(diffs, DeepEquals, []string{"SCALE_NUM: 2 != 1", "STORE_LIMIT: 2000 != ", "tikv num: 4 != 3"})
+	c.Assert(cur.ConfigDiff(nil), HasLen, 0)
+
+	header := cur.Header()
+	c.Assert(strings.Contains(header, "pd version: v4.0.8"), IsTrue)
+	c.Assert(strings.Contains(header, "SCALE_NUM: 2"), IsTrue)
+	c.Assert(strings.Contains(header, "prometheus warnings"), IsFalse)
+	cur.MetricWarnings = 2
+	c.Assert(strings.Contains(cur.Header(), "prometheus warnings: 2"), IsTrue)
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+)
+
+const (
+	encGroupSize = 8
+	encMarker    = byte(0xFF)
+	signMask     = uint64(0x8000000000000000)
+)
+
+// EncodeBytes encodes data in the memcomparable format, which is how TiKV keys are seen by PD.
+func EncodeBytes(data []byte) []byte {
+	ret := make([]byte, 0, (len(data)/encGroupSize+1)*(encGroupSize+1))
+	for idx := 0; idx <= len(data); idx += encGroupSize {
+		remain := len(data) - idx
+		padCount := 0
+		if remain >= encGroupSize {
+			ret = append(ret, data[idx:idx+encGroupSize]...)
+		} else {
+			padCount = encGroupSize - remain
+			ret = append(ret, data[idx:]...)
+			ret = append(ret, make([]byte, padCount)...)
+		}
+		ret = append(ret, encMarker-byte(padCount))
+	}
+	return ret
+}
+
+// TableKeyRange returns the encoded range of all keys of a table.
+func TableKeyRange(tableID int64) (start, end []byte) {
+	prefix := func(id int64) []byte {
+		key := []byte{'t'}
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(id)^signMask)
+		return EncodeBytes(append(key, b[:]...))
+	}
+	return prefix(tableID), prefix(tableID + 1)
+}
+
+// PlacementRegion is a region with the stores of its peers, keys are encoded.
+type PlacementRegion struct {
+	ID       uint64
+	StartKey []byte
+	EndKey   []byte
+	Stores   []uint64
+	Leader   uint64
+}
+
+// overlaps returns true if the region overlaps [start, end), an empty end is unbounded.
+func (r *PlacementRegion) overlaps(start, end []byte) bool {
+	if len(end) > 0 && bytes.Compare(r.StartKey, end) >= 0 {
+		return false
+	}
+	return len(r.EndKey) == 0 || bytes.Compare(r.EndKey, start) > 0
+}
+
+// IsolationViolations counts regions which have two peers with the same value of the label,
+// a peer on a store without the label is a violation too.
+func IsolationViolations(regions []PlacementRegion, storeLabels map[uint64]map[string]string, label string) int {
+	count := 0
+	for _, r := range regions {
+		seen := make(map[string]struct{}, len(r.Stores))
+		for _, store := range r.Stores {
+			value, ok := storeLabels[store][label]
+			if _, dup := seen[value]; !ok || dup {
+				count++
+				break
+			}
+			seen[value] = struct{}{}
+		}
+	}
+	return count
+}
+
+// LeaderViolations counts regions in [start, end) whose leader is not on a store with the label value.
+func LeaderViolations(regions []PlacementRegion, storeLabels map[uint64]map[string]string, label, value string, start, end []byte) int {
+	count := 0
+	for i := range regions {
+		r := &regions[i]
+		if r.overlaps(start, end) && storeLabels[r.Leader][label] != value {
+			count++
+		}
+	}
+	return count
+}
+
+// AssignZones returns the zone of each up store which has no label of the zone key, zones are
+// assigned round-robin in the order of store ids, so that each zone has a store if there are enough.
+func AssignZones(stores []StoreStats, label string, zones []string) map[uint64]string {
+	var ids []uint64
+	for _, s := range stores {
+		if _, ok := s.Labels[label]; s.State == StoreUp && !ok {
+			ids = append(ids, s.ID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	ret := make(map[uint64]string, len(ids))
+	for i, id := range ids {
+		if len(zones) > 0 {
+			ret[id] = zones[i%len(zones)]
+		}
+	}
+	return ret
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"encoding/hex"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testPlacementSuite{})
+
+type testPlacementSuite struct{}
+
+func (s *testPlacementSuite) TestEncodeBytes(c *C) {
+	c.Assert(EncodeBytes(nil), DeepEquals, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0xF7})
+	c.Assert(EncodeBytes([]byte{1, 2, 3}), DeepEquals, []byte{1, 2, 3, 0, 0, 0, 0, 0, 0xFA})
+	c.Assert(EncodeBytes([]byte{1, 2, 3, 4, 5, 6, 7, 8}), DeepEquals,
+		[]byte{1, 2, 3, 4, 5, 6, 7, 8, 0xFF, 0, 0, 0, 0, 0, 0, 0, 0, 0xF7})
+
+	start, end := TableKeyRange(45)
+	c.Assert(hex.EncodeToString(start), Equals, "7480000000000000ff2d00000000000000f8")
+	c.Assert(hex.EncodeToString(end), Equals, "7480000000000000ff2e00000000000000f8")
+}
+
+func (s *testPlacementSuite) TestViolations(c *C) {
+	labels := map[uint64]map[string]string{
+		1: {"zone": "z1"},
+		2: {"zone": "z2"},
+		3: {"zone": "z3"},
+		4: {"zone": "z1"},
+		5: {},
+	}
+	start, end := TableKeyRange(45)
+	_, before := TableKeyRange(44)
+	regions := []PlacementRegion{
+		{ID: 1, EndKey: before, Stores: []uint64{1, 2, 3}, Leader: 2},
+		{ID: 2, StartKey: before, EndKey: end, Stores: []uint64{1, 2, 4}, Leader: 1},
+		{ID: 3, StartKey: end, Stores: []uint64{2, 3, 5}, Leader: 3},
+	}
+	c.Assert(IsolationViolations(regions, labels, "zone"), Equals, 2)
+	c.Assert(LeaderViolations(regions, labels, "zone", "z1", start, end), Equals, 0)
+	regions[1].Leader = 2
+	c.Assert(LeaderViolations(regions, labels, "zone", "z1", start, end), Equals, 1)
+	// region 1 is before the table
+	c.Assert(LeaderViolations(regions, labels, "zone", "z1", nil, end), Equals, 2)
+	c.Assert(LeaderViolations(regions, labels, "zone", "z1", nil, nil), Equals, 3)
+}
+
+func (s *testPlacementSuite) TestAssignZones(c *C) {
+	stores := []StoreStats{
+		{ID: 4, State: StoreUp},
+		{ID: 1, State: StoreUp},
+		{ID: 2, State: StoreUp, Labels: map[string]string{"zone": "z9"}},
+		{ID: 3, State: StoreUp, Labels: map[string]string{"host": "h3"}},
+		{ID: 5, State: StoreTombstone},
+	}
+	c.Assert(AssignZones(stores, "zone", []string{"z1", "z2"}), DeepEquals, map[uint64]string{1: "z1", 3: "z2", 4: "z1"})
+	c.Assert(AssignZones(stores, "zone", nil), HasLen, 0)
+}
@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// MetricDiff is a metric compared between the last and current report.
+type MetricDiff struct {
+	Category string   `json:"category"`
+	Name     string   `json:"name"`
+	Last     *float64 `json:"last,omitempty"`
+	Cur      float64  `json:"cur"`
+	Delta    float64  `json:"delta"`
+	// DeltaUndefined is true if last is 0 but cur is not, Delta is 0 then.
+	DeltaUndefined bool      `json:"delta_undefined,omitempty"`
+	Better         Direction `json:"better,omitempty"`
+	Threshold      float64   `json:"threshold"`
+	Verdict        Verdict   `json:"verdict"`
+}
+
+// Comparison is the machine-readable result of a bench.
+type Comparison struct {
+	Case    string       `json:"case"`
+	Meta    *Metadata    `json:"meta,omitempty"`
+	Metrics []MetricDiff `json:"metrics"`
+}
+
+// Renderer writes a Comparison in a format.
+type Renderer interface {
+	// FileName is the name of the output in artifacts.
+	FileName() string
+	Render(w io.Writer, c *Comparison) error
+}
+
+var renderers = map[string]Renderer{
+	"json":     jsonRenderer{},
+	"csv":      csvRenderer{},
+	"junit":    junitRenderer{},
+	"markdown": markdownRenderer{},
+}
+
+// GetRenderer returns the renderer of format.
+func GetRenderer(format string) (Renderer, error) {
+	if r, ok := renderers[format]; ok {
+		return r, nil
+	}
+	return nil, errors.Errorf("unknown report format %s, support list: %s", format, strings.Join(RendererList(), ","))
+}
+
+// RendererList returns all supported formats.
+func RendererList() []string {
+	var ret []string
+	for format := range renderers {
+		ret = append(ret, format)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatLast(m *MetricDiff) string {
+	if m.Last == nil {
+		return ""
+	}
+	return formatFloat(*m.Last)
+}
+
+type jsonRenderer struct{}
+
+func (jsonRenderer) FileName() string {
+	return "compare.json"
+}
+
+func (jsonRenderer) Render(w io.Writer, c *Comparison) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(c)
+}
+
+type csvRenderer struct{}
+
+func (csvRenderer) FileName() string {
+	return "compare.csv"
+}
+
+func (csvRenderer) Render(w io.Writer, c *Comparison) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"category", "name", "last", "cur", "delta", "better", "threshold", "verdict"}); err != nil {
+		return err
+	}
+	for i := range c.Metrics {
+		m := &c.Metrics[i]
+		record := []string{m.Category, m.Name, formatLast(m), formatFloat(m.Cur), m.FormatDelta(),
+			string(m.Better), formatFloat(m.Threshold), string(m.Verdict)}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitRenderer struct{}
+
+func (junitRenderer) FileName() string {
+	return "compare.xml"
+}
+
+// Render writes one testcase per metric, a regressed metric or a failed run is a failure.
+func (junitRenderer) Render(w io.Writer, c *Comparison) error {
+	suite := junitTestSuite{Name: "bench." + c.Case, Tests: len(c.Metrics)}
+	for i := range c.Metrics {
+		m := &c.Metrics[i]
+		tc := junitTestCase{Name: m.Name, ClassName: c.Case + "." + m.Category}
+		if m.Verdict == VerdictRegressed {
+			suite.Failures++
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%s regressed by %s, threshold is %.2f%%", m.Name, m.FormatDelta(), m.Threshold),
+				Type:    string(m.Verdict),
+				Text:    fmt.Sprintf("last: %s, cur: %s, %s is better", formatLast(m), formatFloat(m.Cur), m.Better),
+			}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	// a failed run, such as diverged data, is a failure even if no metric regresses
+	if c.Meta != nil && c.Meta.Error != "" {
+		suite.Tests++
+		suite.Failures++
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      "run",
+			ClassName: c.Case,
+			Failure:   &junitFailure{Message: c.Meta.Error, Type: "failed"},
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type markdownRenderer struct{}
+
+func (markdownRenderer) FileName() string {
+	return "compare.md"
+}
+
+// Render writes a GitHub-flavored markdown table.
+func (markdownRenderer) Render(w io.Writer, c *Comparison) error {
+	text := fmt.Sprintf("### %s\n\n", c.Case)
+	text += "| category | metric | last | cur | delta | verdict |\n"
+	text += "| --- | --- | ---: | ---: | ---: | --- |\n"
+	for i := range c.Metrics {
+		m := &c.Metrics[i]
+		text += fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n", m.Category, m.Name, formatLast(m), formatFloat(m.Cur), m.FormatDelta(), m.Verdict)
+	}
+	_, err := io.WriteString(w, text)
+	return err
+}
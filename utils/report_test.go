@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+type testReportSuite struct{}
+
+var _ = Suite(&testReportSuite{})
+
+func newTestComparison() *Comparison {
+	last := []float64{100, 0.5, 10}
+	return &Comparison{
+		Case: "scale-out",
+		Metrics: []MetricDiff{
+			NewMetricDiff("balance", "balance_time", &last[0], 150, LowerIsBetter, DefaultThreshold, true),
+			NewMetricDiff("latency", "cur_query_latency", &last[1], 0.5, LowerIsBetter, DefaultThreshold, true),
+			NewMetricDiff("throughput", "ops", &last[2], 20, HigherIsBetter, DefaultThreshold, true),
+			NewMetricDiff("store", "store_num", nil, 4, NoDirection, DefaultThreshold, true),
+		},
+	}
+}
+
+func (s *testReportSuite) TestRenderers(c *C) {
+	cmp := newTestComparison()
+	for _, format := range RendererList() {
+		r, err := GetRenderer(format)
+		c.Assert(err, IsNil)
+		var buf bytes.Buffer
+		c.Assert(r.Render(&buf, cmp), IsNil)
+		out := buf.String()
+		c.Assert(strings.Contains(out, "balance_time"), IsTrue, Commentf("format %s", format))
+		switch format {
+		case "json":
+			var decoded Comparison
+			c.Assert(json.Unmarshal(buf.Bytes(), &decoded), IsNil)
+			c.Assert(decoded.Metrics, HasLen, len(cmp.Metrics))
+			c.Assert(decoded.Metrics[3].Last, IsNil)
+		case "csv":
+			c.Assert(strings.Count(out, "\n"), Equals, len(cmp.Metrics)+1)
+		case "junit":
+			var suite junitTestSuite
+			c.Assert(xml.Unmarshal(buf.Bytes(), &suite), IsNil)
+			c.Assert(suite.Tests, Equals, 4)
+			c.Assert(suite.Failures, Equals, 1)
+			c.Assert(suite.TestCases[0].Failure, NotNil)
+		case "markdown":
+			c.Assert(strings.Contains(out, "| --- |"), IsTrue)
+		}
+	}
+	_, err := GetRenderer("yaml")
+	c.Assert(err, NotNil)
+
+	// a failed run is a failure of junit
+	cmp.Meta = NewMetadata("scale-out")
+	cmp.Meta.Error = "data diverges after run"
+	r, err := GetRenderer("junit")
+	c.Assert(err, IsNil)
+	var buf bytes.Buffer
+	c.Assert(r.Render(&buf, cmp), IsNil)
+	var suite junitTestSuite
+	c.Assert(xml.Unmarshal(buf.Bytes(), &suite), IsNil)
+	c.Assert(suite.Tests, Equals, 5)
+	c.Assert(suite.Failures, Equals, 2)
+	c.Assert(suite.TestCases[4].Failure.Message, Equals, "data diverges after run")
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// ParseLabels parses labels such as "zone=z1,rack=r1,host=h1".
+func ParseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, errors.Errorf("invalid label %s, it should be key=value", part)
+		}
+		labels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return labels, nil
+}
+
+// MatchLabels returns true if labels contains all of expected.
+func MatchLabels(labels, expected map[string]string) bool {
+	for k, v := range expected {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	byteSizeRegexp = regexp.MustCompile(`^([\d.]+)\s*([KMGTP]?i?B?)$`)
+	byteSizeUnits  = map[string]float64{"": 1, "B": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40, "P": 1 << 50}
+)
+
+// ParseByteSize parses a size in binary units such as "1.9TiB" or "512MB", which is how PD shows capacity.
+func ParseByteSize(s string) (uint64, error) {
+	m := byteSizeRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, errors.Errorf("invalid byte size %s", s)
+	}
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, errors.Annotatef(err, "invalid byte size %s", s)
+	}
+	unit := strings.TrimSuffix(strings.TrimSuffix(m[2], "B"), "i")
+	return uint64(value * byteSizeUnits[unit]), nil
+}
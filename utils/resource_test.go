@@ -0,0 +1,44 @@
+package utils
+
+import (
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testResourceSuite{})
+
+type testResourceSuite struct{}
+
+func (s *testResourceSuite) TestParseLabels(c *C) {
+	labels, err := ParseLabels("zone=z1, rack=r1,host=h1")
+	c.Assert(err, IsNil)
+	c.Assert(labels, DeepEquals, map[string]string{"zone": "z1", "rack": "r1", "host": "h1"})
+	labels, err = ParseLabels("")
+	c.Assert(err, IsNil)
+	c.Assert(labels, HasLen, 0)
+	_, err = ParseLabels("zone")
+	c.Assert(err, NotNil)
+
+	c.Assert(MatchLabels(map[string]string{"zone": "z1", "rack": "r1"}, map[string]string{"zone": "z1"}), IsTrue)
+	c.Assert(MatchLabels(map[string]string{"zone": "z1"}, map[string]string{"zone": "z2"}), IsFalse)
+	c.Assert(MatchLabels(nil, nil), IsTrue)
+}
+
+func (s *testResourceSuite) TestParseByteSize(c *C) {
+	for _, t := range []struct {
+		s    string
+		size uint64
+	}{
+		{"0B", 0},
+		{"512MiB", 512 << 20},
+		{"512MB", 512 << 20},
+		{"1.5GiB", 3 << 29},
+		{"2TiB", 2 << 40},
+		{"1024", 1024},
+	} {
+		size, err := ParseByteSize(t.s)
+		c.Assert(err, IsNil, Commentf("%s", t.s))
+		c.Assert(size, Equals, t.size, Commentf("%s", t.s))
+	}
+	_, err := ParseByteSize("1.9XB")
+	c.Assert(err, NotNil)
+}
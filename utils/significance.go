@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"math"
+)
+
+// SignificanceLevel is the p-value under which a change is significant.
+const SignificanceLevel = 0.05
+
+// Summary is the aggregation of samples of a metric.
+type Summary struct {
+	N      int     `json:"n"`
+	Mean   float64 `json:"mean"`
+	Stddev float64 `json:"stddev"`
+	// CILow and CIHigh are the 95% confidence interval of the mean.
+	CILow  float64 `json:"ci_low"`
+	CIHigh float64 `json:"ci_high"`
+}
+
+func meanVar(samples []float64) (mean, variance float64) {
+	n := float64(len(samples))
+	if n == 0 {
+		return 0, 0
+	}
+	for _, v := range samples {
+		mean += v
+	}
+	mean /= n
+	if n < 2 {
+		return mean, 0
+	}
+	for _, v := range samples {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, variance / (n - 1)
+}
+
+// Summarize returns mean, sample standard deviation and 95% confidence interval of samples.
+func Summarize(samples []float64) Summary {
+	mean, variance := meanVar(samples)
+	s := Summary{N: len(samples), Mean: mean, Stddev: math.Sqrt(variance), CILow: mean, CIHigh: mean}
+	if s.N < 2 {
+		return s
+	}
+	half := studentTQuantile(0.975, float64(s.N-1)) * s.Stddev / math.Sqrt(float64(s.N))
+	s.CILow, s.CIHigh = mean-half, mean+half
+	return s
+}
+
+// WelchTTest returns the two-sided p-value of Welch's t-test on two groups of samples.
+// ok is false if any group has less than two samples.
+func WelchTTest(a, b []float64) (p float64, ok bool) {
+	if len(a) < 2 || len(b) < 2 {
+		return 1, false
+	}
+	ma, va := meanVar(a)
+	mb, vb := meanVar(b)
+	va /= float64(len(a))
+	vb /= float64(len(b))
+	if va+vb == 0 {
+		if ma == mb {
+			return 1, true
+		}
+		return 0, true
+	}
+	t := (ma - mb) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/float64(len(a)-1) + vb*vb/float64(len(b)-1))
+	return studentTTwoSided(t, df), true
+}
+
+// IsSignificant returns true if the difference of two groups of samples is statistically significant.
+func IsSignificant(a, b []float64) bool {
+	p, ok := WelchTTest(a, b)
+	return ok && p < SignificanceLevel
+}
+
+// studentTTwoSided returns P(|T| > |t|) of Student's t-distribution with df degrees of freedom.
+func studentTTwoSided(t, df float64) float64 {
+	return regIncBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// studentTQuantile returns x which makes P(T <= x) = q, q should be in (0.5, 1).
+func studentTQuantile(q, df float64) float64 {
+	lo, hi := 0.0, 1.0
+	for 1-studentTTwoSided(hi, df)/2 < q {
+		hi *= 2
+	}
+	for i := 0; i < 100; i++ {
+		mid := (lo + hi) / 2
+		if 1-studentTTwoSided(mid, df)/2 < q {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// regIncBeta returns the regularized incomplete beta function I_x(a, b).
+func regIncBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(math.Log(x)*a + math.Log(1-x)*b + lab - la - lb)
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+// betaContinuedFraction evaluates the continued fraction of the incomplete beta function by Lentz's method.
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIter = 300
+		eps     = 1e-14
+		tiny    = 1e-300
+	)
+	qab, qap, qam := a+b, a+1, a-1
+	c, d := 1.0, 1-qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIter; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < eps {
+			break
+		}
+	}
+	return h
+}
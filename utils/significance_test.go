@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"math"
+
+	. "github.com/pingcap/check"
+)
+
+type testSignificanceSuite struct{}
+
+var _ = Suite(&testSignificanceSuite{})
+
+func almostEqual(a, b, eps float64) bool {
+	return math.Abs(a-b) < eps
+}
+
+func (s *testSignificanceSuite) TestSummarize(c *C) {
+	sum := Summarize([]float64{1, 2, 3, 4, 5})
+	c.Assert(sum.N, Equals, 5)
+	c.Assert(sum.Mean, Equals, 3.0)
+	c.Assert(almostEqual(sum.Stddev, 1.5811, 1e-4), IsTrue)
+	// t(0.975, 4) = 2.7764
+	c.Assert(almostEqual(sum.CIHigh-sum.Mean, 2.7764*1.5811/math.Sqrt(5), 1e-3), IsTrue)
+	c.Assert(almostEqual(sum.Mean-sum.CILow, sum.CIHigh-sum.Mean, 1e-9), IsTrue)
+
+	single := Summarize([]float64{7})
+	c.Assert(single.CILow, Equals, 7.0)
+	c.Assert(single.CIHigh, Equals, 7.0)
+}
+
+func (s *testSignificanceSuite) TestWelchTTest(c *C) {
+	p, ok := WelchTTest([]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10})
+	c.Assert(ok, IsTrue)
+	// t = -5, df = 8
+	c.Assert(almostEqual(p, 0.001053, 1e-5), IsTrue)
+
+	p, ok = WelchTTest([]float64{1, 2, 3}, []float64{1.5, 2.5, 2})
+	c.Assert(ok, IsTrue)
+	c.Assert(p > SignificanceLevel, IsTrue)
+
+	_, ok = WelchTTest([]float64{1}, []float64{1, 2})
+	c.Assert(ok, IsFalse)
+
+	c.Assert(IsSignificant([]float64{3, 3, 3}, []float64{3, 3, 3}), IsFalse)
+	c.Assert(IsSignificant([]float64{3, 3, 3}, []float64{4, 4, 4}), IsTrue)
+}
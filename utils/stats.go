@@ -3,11 +3,14 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"io/ioutil"
+	"math"
 	"reflect"
-	"strconv"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/go-echarts/go-echarts/charts"
+	"github.com/pingcap/errors"
 )
 
 type compareStats interface {
@@ -17,114 +20,740 @@ type compareStats interface {
 	Report() (string, error)
 }
 
-var scaleOutStatsOrder = []string{
-	"BalanceInterval",
-	"PrevBalanceLeaderCount",
-	"PrevBalanceRegionCount",
-	"CurBalanceLeaderCount",
-	"CurBalanceRegionCount",
-	"PrevLatency",
-	"CurLatency",
-	"PrevCompactionRate",
-	"CurCompactionRate",
-	"PrevApplyLog",
-	"CurApplyLog",
-	"PrevDbMutex",
-	"CurDbMutex",
+var _ compareStats = &Stats{}
+
+// Metric is a numeric field of a report.
+// The field is described by the `bench` tag, such as `bench:"name=balance_time,category=balance,unit=s,better=lower"`,
+// `bench:"-"` skips the field. Nested fields inherit category, unit and better from the parent.
+type Metric struct {
+	Name     string    `json:"name"`
+	Category string    `json:"category,omitempty"`
+	Unit     string    `json:"unit,omitempty"`
+	Better   Direction `json:"better,omitempty"`
+	Value    float64   `json:"value"`
 }
 
-// ScaleOutOnce is scale out stats once
-type ScaleOutOnce struct {
-	BalanceInterval        int     `json:"BalanceInterval"`
-	PrevBalanceLeaderCount int     `json:"PrevBalanceLeaderCount"`
-	PrevBalanceRegionCount int     `json:"PrevBalanceRegionCount"`
-	CurBalanceLeaderCount  int     `json:"CurBalanceLeaderCount"`
-	CurBalanceRegionCount  int     `json:"CurBalanceRegionCount"`
-	PrevLatency            float64 `json:"PrevLatency"`
-	CurLatency             float64 `json:"CurLatency"`
-	PrevCompactionRate     float64 `json:"PrevCompactionRate"`
-	CurCompactionRate      float64 `json:"CurCompactionRate"`
-	PrevApplyLog           float64 `json:"PrevApplyLog"`
-	CurApplyLog            float64 `json:"CurApplyLog"`
-	PrevDbMutex            float64 `json:"PrevDbMutex"`
-	CurDbMutex             float64 `json:"CurDbMutex"`
+type metricTag struct {
+	name     string
+	category string
+	unit     string
+	better   Direction
+	skip     bool
 }
 
-// ScaleOutStats is a compare of two ScaleOutOnce
-type ScaleOutStats struct {
-	compareStats
-	statsMap *map[string][2]float64
+func parseMetricTag(tag string) metricTag {
+	var t metricTag
+	if tag == "-" {
+		t.skip = true
+		return t
+	}
+	for _, part := range strings.Split(tag, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch strings.TrimSpace(kv[0]) {
+		case "name":
+			t.name = kv[1]
+		case "category":
+			t.category = kv[1]
+		case "unit":
+			t.unit = kv[1]
+		case "better":
+			t.better = Direction(kv[1])
+		}
+	}
+	return t
+}
+
+func (t metricTag) inherit(parent metricTag) metricTag {
+	if t.category == "" {
+		t.category = parent.category
+	}
+	if t.unit == "" {
+		t.unit = parent.unit
+	}
+	if t.better == NoDirection {
+		t.better = parent.better
+	}
+	return t
+}
+
+func joinName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Missing is the names of metrics which have no data in a report, such as PrevLatency.
+// They are left as 0, so that they are skipped in comparisons instead of being taken as a real 0.
+type Missing []string
+
+var missingType = reflect.TypeOf(Missing{})
+
+// UnionMissing returns missing metrics of all lists, each metric appears once in the order of first appearance.
+func UnionMissing(lists ...[]string) Missing {
+	var ret Missing
+	seen := make(map[string]struct{})
+	for _, list := range lists {
+		for _, m := range list {
+			if _, ok := seen[m]; !ok {
+				seen[m] = struct{}{}
+				ret = append(ret, m)
+			}
+		}
+	}
+	return ret
+}
+
+// missingOf returns the missing metrics which are recorded in the Missing fields of a report.
+func missingOf(v interface{}) map[string]struct{} {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	missing := make(map[string]struct{})
+	for i := 0; i < rv.NumField(); i++ {
+		if rv.Field(i).Type() != missingType {
+			continue
+		}
+		for _, name := range rv.Field(i).Interface().(Missing) {
+			missing[name] = struct{}{}
+		}
+	}
+	return missing
+}
+
+// ParseReportMetrics is ParseMetrics without the missing metrics of the report.
+func ParseReportMetrics(v interface{}) ([]Metric, error) {
+	metrics, err := ParseMetrics(v)
+	if err != nil {
+		return nil, err
+	}
+	missing := missingOf(v)
+	if len(missing) == 0 {
+		return metrics, nil
+	}
+	ret := metrics[:0]
+	for _, m := range metrics {
+		if _, ok := missing[m.Name]; !ok {
+			ret = append(ret, m)
+		}
+	}
+	return ret, nil
+}
+
+// ParseMetrics flattens numeric fields of a struct or a map into metrics.
+// The order follows the struct fields and the sorted map keys.
+func ParseMetrics(v interface{}) ([]Metric, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, errors.New("stats is nil")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct && rv.Kind() != reflect.Map {
+		return nil, errors.Errorf("stats should be a struct or a map, but it is %s", rv.Kind())
+	}
+	var metrics []Metric
+	collectMetrics("", metricTag{}, rv, &metrics)
+	return metrics, nil
+}
+
+func collectMetrics(name string, tag metricTag, v reflect.Value, metrics *[]Metric) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			collectMetrics(name, tag, v.Elem(), metrics)
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" { // unexported
+				continue
+			}
+			fieldTag := parseMetricTag(field.Tag.Get("bench"))
+			if fieldTag.skip {
+				continue
+			}
+			fieldName := name
+			if !field.Anonymous {
+				if fieldTag.name == "" {
+					fieldTag.name = field.Name
+				}
+				fieldName = joinName(name, fieldTag.name)
+			}
+			collectMetrics(fieldName, fieldTag.inherit(tag), v.Field(i), metrics)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			collectMetrics(joinName(name, k), tag, v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())), metrics)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		*metrics = append(*metrics, newMetric(name, tag, float64(v.Int())))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		*metrics = append(*metrics, newMetric(name, tag, float64(v.Uint())))
+	case reflect.Float32, reflect.Float64:
+		*metrics = append(*metrics, newMetric(name, tag, v.Float()))
+	}
+}
+
+func newMetric(name string, tag metricTag, value float64) Metric {
+	return Metric{Name: name, Category: tag.category, Unit: tag.unit, Better: tag.better, Value: value}
+}
+
+// MetricPair is a metric in the last and current report.
+type MetricPair struct {
+	Name     string    `json:"name"`
+	Category string    `json:"category,omitempty"`
+	Unit     string    `json:"unit,omitempty"`
+	Better   Direction `json:"better,omitempty"`
+	Last     float64   `json:"last"`
+	Cur      float64   `json:"cur"`
+	// LastSamples and CurSamples are the values of each iteration, they are empty if there is only one iteration.
+	LastSamples []float64 `json:"last_samples,omitempty"`
+	CurSamples  []float64 `json:"cur_samples,omitempty"`
+}
+
+// sampled returns true if both reports have samples of several iterations, so that they can be tested for significance.
+func (p *MetricPair) sampled() bool {
+	return len(p.LastSamples) > 1 && len(p.CurSamples) > 1
+}
+
+// Iterations is embedded in a report of several iterations, it keeps the value of each metric in every iteration
+// and their summary. It is empty if there is only one iteration.
+type Iterations struct {
+	// Samples is the value of each metric in every iteration.
+	Samples map[string][]float64 `json:"Samples,omitempty"`
+	// Summary is the aggregation of samples of each metric.
+	Summary map[string]Summary `json:"Summary,omitempty"`
+}
+
+func (it *Iterations) iterations() *Iterations {
+	return it
+}
+
+// iterated is a report which embeds Iterations.
+type iterated interface {
+	iterations() *Iterations
+}
+
+func metricSamples(report interface{}) map[string][]float64 {
+	if r, ok := report.(iterated); ok {
+		return r.iterations().Samples
+	}
+	return nil
+}
+
+// CollectSamples returns the value of each metric in every report, all is a slice of reports.
+func CollectSamples(all interface{}) (map[string][]float64, error) {
+	rv := reflect.ValueOf(all)
+	if rv.Kind() != reflect.Slice {
+		return nil, errors.Errorf("reports should be a slice, but it is %s", rv.Kind())
+	}
+	samples := make(map[string][]float64)
+	for i := 0; i < rv.Len(); i++ {
+		metrics, err := ParseMetrics(rv.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range metrics {
+			samples[m.Name] = append(samples[m.Name], m.Value)
+		}
+	}
+	return samples, nil
+}
+
+// Stats is a compare of two reports of any struct or metric map.
+type Stats struct {
+	typ   reflect.Type
+	pairs []MetricPair
+	// Title of the chart.
+	Title string
+	// Threshold is used to highlight regressions in the chart, it is DefaultThreshold if it is 0.
+	Threshold float64
+}
+
+func (s *Stats) title() string {
+	if s.Title == "" {
+		return "stats"
+	}
+	return s.Title
+}
+
+func (s *Stats) threshold() float64 {
+	if s.Threshold == 0 {
+		return DefaultThreshold
+	}
+	return s.Threshold
+}
+
+// NewStats returns Stats of reports which are decoded into the type of proto.
+func NewStats(proto interface{}) *Stats {
+	t := reflect.TypeOf(proto)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return &Stats{typ: t}
+}
+
+func (s *Stats) decode(data string) (interface{}, error) {
+	if s.typ == nil {
+		return nil, errors.New("stats type is not set")
+	}
+	v := reflect.New(s.typ)
+	if err := json.Unmarshal([]byte(data), v.Interface()); err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
 }
 
 // Init data
-func (s *ScaleOutStats) Init(last, cur string) error {
+func (s *Stats) Init(last, cur string) error {
 	if last == "" || cur == "" {
 		return nil
 	}
-	var lastStats, curStats ScaleOutOnce
-	var err error
-	err = json.Unmarshal([]byte(last), &lastStats)
+	lastStats, err := s.decode(last)
 	if err != nil {
 		return err
 	}
-	err = json.Unmarshal([]byte(cur), &curStats)
+	curStats, err := s.decode(cur)
 	if err != nil {
 		return err
 	}
-	m := make(map[string][2]float64)
-	t := reflect.TypeOf(lastStats)
-	v1 := reflect.ValueOf(lastStats)
-	v2 := reflect.ValueOf(curStats)
-	for i := 0; i < v1.NumField(); i++ {
-		var val1, val2 float64
-		if t.Field(i).Type.String() == "int" {
-			val1 = float64(v1.Field(i).Int())
-			val2 = float64(v2.Field(i).Int())
-		} else {
-			val1 = v1.Field(i).Float()
-			val2 = v2.Field(i).Float()
-		}
-		m[t.Field(i).Name] = [2]float64{val1, val2}
-	}
-	s.statsMap = &m
-	return nil
+	return s.InitFrom(lastStats, curStats)
 }
 
-// CollectFrom file report
-func (s *ScaleOutStats) CollectFrom(fileName string) error {
-	// todo: load from file
+// InitFrom decoded reports, only metrics which exist in both reports and are not missing in any of them are compared.
+func (s *Stats) InitFrom(last, cur interface{}) error {
+	lastMetrics, err := ParseReportMetrics(last)
+	if err != nil {
+		return err
+	}
+	curMetrics, err := ParseReportMetrics(cur)
+	if err != nil {
+		return err
+	}
+	lastValues := make(map[string]float64, len(lastMetrics))
+	for _, m := range lastMetrics {
+		lastValues[m.Name] = m.Value
+	}
+	lastSamples, curSamples := metricSamples(last), metricSamples(cur)
+	s.pairs = s.pairs[:0]
+	for _, m := range curMetrics {
+		lastValue, ok := lastValues[m.Name]
+		if !ok {
+			continue
+		}
+		s.pairs = append(s.pairs, MetricPair{
+			Name:        m.Name,
+			Category:    m.Category,
+			Unit:        m.Unit,
+			Better:      m.Better,
+			Last:        lastValue,
+			Cur:         m.Value,
+			LastSamples: lastSamples[m.Name],
+			CurSamples:  curSamples[m.Name],
+		})
+	}
 	return nil
 }
 
-// RenderTo visualization
-func (s *ScaleOutStats) RenderTo(fileName string) error {
-	m := *s.statsMap
-	var lastData, curData []float64
-	for _, stat := range scaleOutStatsOrder {
-		mid := (m[stat][0] + m[stat][1]) / 2
-		lastData = append(lastData, m[stat][0]/(mid+1e-6))
-		curData = append(curData, m[stat][1]/(mid+1e-6))
+// Pairs returns the compared metrics.
+func (s *Stats) Pairs() []MetricPair {
+	return s.pairs
+}
+
+// CollectFrom file report, the file is a json object with the last and current report, such as {"last": {}, "cur": {}}.
+func (s *Stats) CollectFrom(fileName string) error {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	var reports struct {
+		Last json.RawMessage `json:"last"`
+		Cur  json.RawMessage `json:"cur"`
+	}
+	if err := json.Unmarshal(data, &reports); err != nil {
+		return err
+	}
+	return s.Init(string(reports.Last), string(reports.Cur))
+}
+
+// Compare returns the machine-readable comparison, a change of metrics with samples of several iterations
+// is only marked if it is significant.
+func (s *Stats) Compare(caseName string, threshold float64) *Comparison {
+	cmp := &Comparison{Case: caseName}
+	for i := range s.pairs {
+		p := &s.pairs[i]
+		last := p.Last
+		significant := !p.sampled() || IsSignificant(p.LastSamples, p.CurSamples)
+		cmp.Metrics = append(cmp.Metrics, NewMetricDiff(p.Category, p.Name, &last, p.Cur, p.Better, threshold, significant))
+	}
+	return cmp
+}
+
+// BaselineComparison returns the comparison of the first report, which has nothing to compare with.
+// Missing metrics are skipped.
+func BaselineComparison(caseName string, report interface{}) (*Comparison, error) {
+	metrics, err := ParseReportMetrics(report)
+	if err != nil {
+		return nil, err
 	}
-	var xAxis []string
-	for i := range scaleOutStatsOrder {
-		xAxis = append(xAxis, "p"+strconv.Itoa(i))
+	cmp := &Comparison{Case: caseName}
+	for _, m := range metrics {
+		cmp.Metrics = append(cmp.Metrics, NewMetricDiff(m.Category, m.Name, nil, m.Value, m.Better, 0, true))
 	}
-	bar := charts.NewBar()
-	bar.SetGlobalOptions(charts.TitleOpts{Title: "scale out stats"}, charts.ToolboxOpts{Show: true})
-	bar.AddXAxis(xAxis).
-		AddYAxis("last", lastData).
-		AddYAxis("cur", curData)
-	f, _ := os.Create(fileName)
-	return bar.Render(f)
+	return cmp, nil
 }
 
 // Report stats
-func (s *ScaleOutStats) Report() (string, error) {
-	m := *s.statsMap
+func (s *Stats) Report() (string, error) {
 	text := ""
-	for i, s := range scaleOutStatsOrder {
-		text += "p" + strconv.Itoa(i) + ": " + s + "\n"
-		text += fmt.Sprintf("PR(last, red) is %.6f\n", m[s][0])
+	for i := range s.pairs {
+		p := &s.pairs[i]
+		text += metricLabel(p.Name, p.Unit) + ": "
+		text += fmt.Sprintf("last is %.6f, cur is %.6f", p.Last, p.Cur)
+		if p.sampled() {
+			sum := Summarize(p.CurSamples)
+			pValue, _ := WelchTTest(p.LastSamples, p.CurSamples)
+			text += fmt.Sprintf(" ±%.6f (n=%d) p=%.4f", sum.CIHigh-sum.Mean, sum.N, pValue)
+		}
+		text += "\n"
 	}
 	return text, nil
 }
+
+// ScaleOutOnce is scale out stats once, fields of a category are kept together as the report is grouped by category.
+type ScaleOutOnce struct {
+	BalanceInterval int `json:"BalanceInterval" bench:"category=balance,unit=s,better=lower"`
+	// RegionCount is the number of regions after balance.
+	RegionCount int `json:"RegionCount,omitempty" bench:"category=balance"`
+	// DeployTime is the time until the platform deploys the new stores.
+	DeployTime float64 `json:"DeployTime,omitempty" bench:"category=scale,unit=s,better=lower"`
+	// RegisterTime is the time from the stores are deployed until they are up in PD.
+	RegisterTime           float64 `json:"RegisterTime,omitempty" bench:"category=scale,unit=s,better=lower"`
+	PrevBalanceLeaderCount int     `json:"PrevBalanceLeaderCount" bench:"category=schedule"`
+	PrevBalanceRegionCount int     `json:"PrevBalanceRegionCount" bench:"category=schedule"`
+	CurBalanceLeaderCount  int     `json:"CurBalanceLeaderCount" bench:"category=schedule"`
+	CurBalanceRegionCount  int     `json:"CurBalanceRegionCount" bench:"category=schedule"`
+	// BalanceLeaderOperators and BalanceRegionOperators are the operators scheduled from adding stores until balance.
+	BalanceLeaderOperators int     `json:"BalanceLeaderOperators" bench:"category=schedule,better=lower"`
+	BalanceRegionOperators int     `json:"BalanceRegionOperators" bench:"category=schedule,better=lower"`
+	PrevCompactionRate     float64 `json:"PrevCompactionRate" bench:"category=compaction,unit=B"`
+	CurCompactionRate      float64 `json:"CurCompactionRate" bench:"category=compaction,unit=B"`
+	// CompactionFlow is the bytes compacted from adding stores until balance.
+	CompactionFlow float64 `json:"CompactionFlow" bench:"category=compaction,unit=B,better=lower"`
+	PrevLatency    float64 `json:"PrevLatency" bench:"category=latency,unit=s,better=lower"`
+	CurLatency     float64 `json:"CurLatency" bench:"category=latency,unit=s,better=lower"`
+	PrevApplyLog   float64 `json:"PrevApplyLog" bench:"category=latency,unit=s,better=lower"`
+	CurApplyLog    float64 `json:"CurApplyLog" bench:"category=latency,unit=s,better=lower"`
+	PrevDbMutex    float64 `json:"PrevDbMutex" bench:"category=latency,unit=s,better=lower"`
+	CurDbMutex     float64 `json:"CurDbMutex" bench:"category=latency,unit=s,better=lower"`
+	// StoreRegionScore is the region score of each store after balance.
+	StoreRegionScore map[string]float64 `json:"StoreRegionScore,omitempty" bench:"-"`
+	// Stores is the stats of each store from PD after balance.
+	Stores []StoreStats `json:"Stores,omitempty" bench:"-"`
+	// Steps is the stats of each step if stores are added in steps.
+	Steps []ScaleOutStep `json:"Steps,omitempty" bench:"-"`
+	// Missing records the metrics which have no data, they are left as 0 and are not compared.
+	Missing    Missing `json:"Missing,omitempty"`
+	Iterations `bench:"-"`
+	// Meta describes the run which produces the stats.
+	Meta *Metadata `json:"Meta,omitempty" bench:"-"`
+}
+
+// ScaleOutStep is the stats of a step of a stepped scale out, from the stores are added until balanced.
+type ScaleOutStep struct {
+	// Stores is the store count after the step.
+	Stores int `json:"Stores" bench:"-"`
+	// Added is the count of stores added by the step.
+	Added       int     `json:"Added" bench:"-"`
+	BalanceTime float64 `json:"BalanceTime" bench:"category=balance,unit=s,better=lower"`
+	// BalanceTimePerStore is BalanceTime divided by Added, it is flat if the cost of scheduling scales linearly.
+	BalanceTimePerStore float64 `json:"BalanceTimePerStore" bench:"category=balance,unit=s,better=lower"`
+	RegionOperators     int     `json:"RegionOperators" bench:"category=schedule,better=lower"`
+	// DataMoved is the size of snapshots which are sent for moving regions.
+	DataMoved float64 `json:"DataMoved" bench:"category=schedule,unit=B,better=lower"`
+	Latency   float64 `json:"Latency" bench:"category=latency,unit=s,better=lower"`
+	// DeployTime and RegisterTime are the time to deploy the stores of the step and for them to be up in PD.
+	DeployTime   float64 `json:"DeployTime" bench:"category=scale,unit=s,better=lower"`
+	RegisterTime float64 `json:"RegisterTime" bench:"category=scale,unit=s,better=lower"`
+}
+
+// ScaleOutCurve returns the steps as points labeled by the store count.
+func ScaleOutCurve(steps []ScaleOutStep) ([]TrendPoint, error) {
+	points := make([]TrendPoint, 0, len(steps))
+	for i := range steps {
+		metrics, err := ParseMetrics(&steps[i])
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, TrendPoint{Label: fmt.Sprintf("%d stores", steps[i].Stores), Metrics: metrics})
+	}
+	return points, nil
+}
+
+// SimulatorOnce is the result of a simulator run.
+type SimulatorOnce struct {
+	// Duration is the time for the simulator to finish its case.
+	Duration float64 `json:"Duration" bench:"category=balance,unit=s,better=lower"`
+}
+
+// PDLeaderSwitchOnce is the stats of a PD leader switch.
+type PDLeaderSwitchOnce struct {
+	// ElectionTime is the time from the switch until a new leader serves.
+	ElectionTime float64 `json:"ElectionTime" bench:"category=failover,unit=s,better=lower"`
+	// TsoUnavailable is the longest time in which no write of the workload succeeds.
+	TsoUnavailable float64 `json:"TsoUnavailable" bench:"category=failover,unit=s,better=lower"`
+	// StoreHeartbeatResume is the time until all stores send heartbeats to the new leader.
+	StoreHeartbeatResume float64 `json:"StoreHeartbeatResume" bench:"category=failover,unit=s,better=lower"`
+	// RegionHeartbeatResume is the time until region heartbeats are handled again, its precision is the scrape interval.
+	RegionHeartbeatResume float64 `json:"RegionHeartbeatResume" bench:"category=failover,unit=s,better=lower"`
+	FailedQueries         int     `json:"FailedQueries" bench:"category=failover,better=lower"`
+	PrevP99Latency        float64 `json:"PrevP99Latency" bench:"category=latency,unit=s,better=lower"`
+	SwitchP99Latency      float64 `json:"SwitchP99Latency" bench:"category=latency,unit=s,better=lower"`
+	SwitchMaxLatency      float64 `json:"SwitchMaxLatency" bench:"category=latency,unit=s,better=lower"`
+	// Missing records the metrics which have no data, they are left as 0 and are not compared.
+	Missing    Missing `json:"Missing,omitempty"`
+	Iterations `bench:"-"`
+	// Meta describes the run which produces the stats.
+	Meta *Metadata `json:"Meta,omitempty" bench:"-"`
+}
+
+// RollingRestartStore is the stats of restarting a store.
+type RollingRestartStore struct {
+	// EvictDuration is the time until all leaders are evicted from the store.
+	EvictDuration float64 `json:"EvictDuration"`
+	// RestartDuration is the time until the restarted store is up again.
+	RestartDuration float64 `json:"RestartDuration"`
+	// LeaderRestore is the time until leaders are balanced after the scheduler is removed.
+	LeaderRestore float64 `json:"LeaderRestore"`
+	// P99Latency is the latency of the workload while the store is restarted.
+	P99Latency float64 `json:"P99Latency"`
+}
+
+// RollingRestartOnce is the stats of restarting all stores one by one.
+type RollingRestartOnce struct {
+	TotalDuration    float64 `json:"TotalDuration" bench:"category=restart,unit=s,better=lower"`
+	MaxEvictDuration float64 `json:"MaxEvictDuration" bench:"category=restart,unit=s,better=lower"`
+	MaxLeaderRestore float64 `json:"MaxLeaderRestore" bench:"category=restart,unit=s,better=lower"`
+	FailedQueries    int     `json:"FailedQueries" bench:"category=restart,better=lower"`
+	PrevP99Latency   float64 `json:"PrevP99Latency" bench:"category=latency,unit=s,better=lower"`
+	MaxP99Latency    float64 `json:"MaxP99Latency" bench:"category=latency,unit=s,better=lower"`
+	// Stores is the stats of each store by store id.
+	Stores     map[string]RollingRestartStore `json:"Stores,omitempty" bench:"name=store,category=store,unit=s,better=lower"`
+	Iterations `bench:"-"`
+	// Meta describes the run which produces the stats.
+	Meta *Metadata `json:"Meta,omitempty" bench:"-"`
+}
+
+// ElasticityCycle is the stats of a cycle of scaling out and scaling in.
+type ElasticityCycle struct {
+	// ScaleOutBalance is the time from adding stores until balanced.
+	ScaleOutBalance float64 `json:"ScaleOutBalance"`
+	// ScaleInDrain is the time from deleting stores until they are tombstone.
+	ScaleInDrain float64 `json:"ScaleInDrain"`
+	// ScaleInBalance is the time from deleting stores until balanced.
+	ScaleInBalance float64 `json:"ScaleInBalance"`
+}
+
+// ElasticityOnce is the stats of cycles of scaling out and scaling in by the same number of stores.
+type ElasticityOnce struct {
+	ScaleOutBalance float64 `json:"ScaleOutBalance" bench:"category=scale-out,unit=s,better=lower"`
+	// ScaleOutOperators, ScaleInOperators and LeftoverOperators are the mean of cycles.
+	ScaleOutOperators float64 `json:"ScaleOutOperators" bench:"category=scale-out,better=lower"`
+	ScaleInDrain      float64 `json:"ScaleInDrain" bench:"category=scale-in,unit=s,better=lower"`
+	ScaleInBalance    float64 `json:"ScaleInBalance" bench:"category=scale-in,unit=s,better=lower"`
+	ScaleInOperators  float64 `json:"ScaleInOperators" bench:"category=scale-in,better=lower"`
+	// Asymmetry is ScaleInBalance divided by ScaleOutBalance.
+	Asymmetry float64 `json:"Asymmetry" bench:"category=cycle"`
+	// LeftoverOperators is the count of pending operators when stores are balanced.
+	LeftoverOperators float64 `json:"LeftoverOperators" bench:"category=cycle,better=lower"`
+	// Cycles is the stats of each cycle by its index from 1.
+	Cycles map[string]ElasticityCycle `json:"Cycles,omitempty" bench:"name=cycle,category=cycle,unit=s,better=lower"`
+	// Missing records the metrics which have no data, they are left as 0 and are not compared.
+	Missing    Missing `json:"Missing,omitempty"`
+	Iterations `bench:"-"`
+	// Meta describes the run which produces the stats.
+	Meta *Metadata `json:"Meta,omitempty" bench:"-"`
+}
+
+// HeterogeneousOnce is the stats of scaling out by stores which differ from the others in capacity or weight.
+type HeterogeneousOnce struct {
+	BalanceTime float64 `json:"BalanceTime" bench:"category=balance,unit=s,better=lower"`
+	// RegionScoreSpread is the spread of region scores of up stores after balance.
+	RegionScoreSpread float64 `json:"RegionScoreSpread" bench:"category=balance,better=lower"`
+	// NormalizedScoreSpread is the spread of region scores per GiB of capacity.
+	NormalizedScoreSpread float64 `json:"NormalizedScoreSpread" bench:"category=capacity,better=lower"`
+	// UsedRatioSpread is the spread of used ratios of capacity.
+	UsedRatioSpread float64 `json:"UsedRatioSpread" bench:"category=capacity,better=lower"`
+	// Stores is the stats of each up store relative to its capacity by store id.
+	Stores     map[string]CapacityStore `json:"Stores,omitempty" bench:"name=store,category=store"`
+	Iterations `bench:"-"`
+	// Meta describes the run which produces the stats.
+	Meta *Metadata `json:"Meta,omitempty" bench:"-"`
+}
+
+// ViolationSample is the count of regions which violate placement at a time since the scale out.
+type ViolationSample struct {
+	Seconds float64 `json:"Seconds"`
+	Count   int     `json:"Count"`
+}
+
+// PlacementOnce is the stats of scaling out a cluster with placement rules.
+type PlacementOnce struct {
+	// ComplianceTime is the time from adding stores until no region violates placement and no operator is pending.
+	ComplianceTime  float64 `json:"ComplianceTime" bench:"category=placement,unit=s,better=lower"`
+	MaxViolations   int     `json:"MaxViolations" bench:"category=placement,better=lower"`
+	FinalViolations int     `json:"FinalViolations" bench:"category=placement,better=lower"`
+	Operators       int     `json:"Operators" bench:"category=schedule,better=lower"`
+	// Violations is the count of violations over time.
+	Violations []ViolationSample `json:"Violations,omitempty" bench:"-"`
+	// Missing records the metrics which have no data, they are left as 0 and are not compared.
+	Missing    Missing `json:"Missing,omitempty"`
+	Iterations `bench:"-"`
+	// Meta describes the run which produces the stats.
+	Meta *Metadata `json:"Meta,omitempty" bench:"-"`
+}
+
+// PDScaleOutOnce is the stats of adding PD members while a workload is running.
+type PDScaleOutOnce struct {
+	// ReadyTime is the time from scaling out until all members serve with a leader.
+	ReadyTime float64 `json:"ReadyTime" bench:"category=membership,unit=s,better=lower"`
+	Members   int     `json:"Members" bench:"category=membership"`
+	// LeaderChanges is the number of leader changes from scaling out until the end of observation.
+	LeaderChanges   int     `json:"LeaderChanges" bench:"category=stability,better=lower"`
+	TsoUnavailable  float64 `json:"TsoUnavailable" bench:"category=stability,unit=s,better=lower"`
+	FailedQueries   int     `json:"FailedQueries" bench:"category=stability,better=lower"`
+	PrevP99Latency  float64 `json:"PrevP99Latency" bench:"category=latency,unit=s,better=lower"`
+	ScaleP99Latency float64 `json:"ScaleP99Latency" bench:"category=latency,unit=s,better=lower"`
+	Iterations      `bench:"-"`
+	// Meta describes the run which produces the stats.
+	Meta *Metadata `json:"Meta,omitempty" bench:"-"`
+}
+
+// TiDBShare is the connections which are served by a TiDB instance.
+type TiDBShare struct {
+	Connections int     `json:"Connections"`
+	Share       float64 `json:"Share"`
+}
+
+// TiDBScaleOutOnce is the stats of adding TiDB instances and spreading new connections over them.
+type TiDBScaleOutOnce struct {
+	// ReadyTime is the time from scaling out until all instances are registered and their status ports respond.
+	ReadyTime float64 `json:"ReadyTime" bench:"category=membership,unit=s,better=lower"`
+	Instances int     `json:"Instances" bench:"category=membership"`
+	// Unreached is the number of registered instances which serve none of the new connections.
+	Unreached int `json:"Unreached" bench:"category=connection,better=lower"`
+	// MaxShare is the largest share of new connections served by one instance.
+	MaxShare         float64 `json:"MaxShare" bench:"category=connection,better=lower"`
+	ConnectionSpread float64 `json:"ConnectionSpread" bench:"category=connection,better=lower"`
+	// Shares is the connections of each instance by its host and port.
+	Shares     map[string]TiDBShare `json:"Shares,omitempty" bench:"name=instance,category=instance"`
+	Iterations `bench:"-"`
+	// Meta describes the run which produces the stats.
+	Meta *Metadata `json:"Meta,omitempty" bench:"-"`
+}
+
+// Mean returns the mean of int and float64 fields of all stats, all is a slice of struct pointers.
+// The result is a pointer of the same struct, other fields are left empty.
+func Mean(all interface{}) interface{} {
+	rv := reflect.ValueOf(all)
+	mean := reflect.New(rv.Type().Elem().Elem())
+	if rv.Len() == 0 {
+		return mean.Interface()
+	}
+	v := mean.Elem()
+	n := float64(rv.Len())
+	for i := 0; i < v.NumField(); i++ {
+		var sum float64
+		switch v.Field(i).Kind() {
+		case reflect.Int:
+			for j := 0; j < rv.Len(); j++ {
+				sum += float64(rv.Index(j).Elem().Field(i).Int())
+			}
+			v.Field(i).SetInt(int64(math.Round(sum / n)))
+		case reflect.Float64:
+			for j := 0; j < rv.Len(); j++ {
+				sum += rv.Index(j).Elem().Field(i).Float()
+			}
+			v.Field(i).SetFloat(sum / n)
+		}
+	}
+	return mean.Interface()
+}
+
+// Aggregate returns the mean of all stats like Mean, all is a slice of struct pointers.
+// Missing metrics of all stats are kept, and if the stats embed Iterations and there are several of them,
+// the value of each metric in every iteration and their summary are kept too.
+func Aggregate(all interface{}) (interface{}, error) {
+	rep := Mean(all)
+	rv := reflect.ValueOf(all)
+	v := reflect.ValueOf(rep).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).Type() != missingType {
+			continue
+		}
+		var missing Missing
+		for j := 0; j < rv.Len(); j++ {
+			missing = UnionMissing(missing, rv.Index(j).Elem().Field(i).Interface().(Missing))
+		}
+		v.Field(i).Set(reflect.ValueOf(missing))
+	}
+	it, ok := rep.(iterated)
+	if !ok || rv.Len() < 2 {
+		return rep, nil
+	}
+	samples, err := CollectSamples(all)
+	if err != nil {
+		return nil, err
+	}
+	it.iterations().Samples = samples
+	it.iterations().Summary = make(map[string]Summary, len(samples))
+	for name, values := range samples {
+		it.iterations().Summary[name] = Summarize(values)
+	}
+	return rep, nil
+}
+
+// MeanScaleOutOnce returns the mean of numeric fields of all stats, other fields are left empty.
+func MeanScaleOutOnce(all []*ScaleOutOnce) *ScaleOutOnce {
+	return Mean(all).(*ScaleOutOnce)
+}
+
+// ScaleOutStats is a compare of two ScaleOutOnce
+type ScaleOutStats struct {
+	Stats
+}
+
+// Init data
+func (s *ScaleOutStats) Init(last, cur string) error {
+	s.typ = reflect.TypeOf(ScaleOutOnce{})
+	if s.Title == "" {
+		s.Title = "scale out stats"
+	}
+	return s.Stats.Init(last, cur)
+}
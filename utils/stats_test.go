@@ -2,6 +2,7 @@ package utils
 
 import (
 	"encoding/json"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -17,21 +18,215 @@ type testStatsSuite struct{}
 var _ = Suite(&testStatsSuite{})
 
 func (s *testStatsSuite) TestScaleOutStats(c *C) {
-	prev := ScaleOutOnce{10, 11, 12, 13,
-		12, 11, 10, 9, 8, 7,
-		6, 5, 4}
-	cur := ScaleOutOnce{10, 9, 8, 7,
-		6, 5, 6, 7, 8, 9,
-		10, 11, 12}
+	prev := ScaleOutOnce{
+		BalanceInterval: 10, PrevBalanceLeaderCount: 11, PrevBalanceRegionCount: 12, CurBalanceLeaderCount: 13,
+		CurBalanceRegionCount: 12, PrevLatency: 11, CurLatency: 10, PrevCompactionRate: 9, CurCompactionRate: 8, PrevApplyLog: 7,
+		CurApplyLog: 6, PrevDbMutex: 5, CurDbMutex: 4, RegionCount: 20, DeployTime: 30, RegisterTime: 40,
+	}
+	cur := ScaleOutOnce{
+		BalanceInterval: 10, PrevBalanceLeaderCount: 9, PrevBalanceRegionCount: 8, CurBalanceLeaderCount: 7,
+		CurBalanceRegionCount: 6, PrevLatency: 5, CurLatency: 6, PrevCompactionRate: 7, CurCompactionRate: 8, PrevApplyLog: 9,
+		CurApplyLog: 10, PrevDbMutex: 11, CurDbMutex: 12, RegionCount: 21, DeployTime: 31, RegisterTime: 41,
+		StoreRegionScore: map[string]float64{"1": 10},
+		Stores:           []StoreStats{{ID: 1, State: StoreUp}},
+		Missing:          []string{"PrevLatency"},
+		Meta:             NewMetadata("scale-out"),
+	}
 	bytes1, _ := json.Marshal(prev)
 	bytes2, _ := json.Marshal(cur)
 	stats := &ScaleOutStats{}
 	err := stats.Init(string(bytes1), string(bytes2))
 	c.Assert(err, IsNil)
-	err = stats.RenderTo("test_scale.html")
+	// PrevLatency is missing in cur, so it is not compared
+	c.Assert(stats.Pairs(), HasLen, 18)
+	for _, pair := range stats.Pairs() {
+		c.Assert(pair.Name, Not(Equals), "PrevLatency")
+	}
+	c.Assert(stats.Pairs()[0], DeepEquals, MetricPair{Name: "BalanceInterval", Category: "balance", Unit: "s", Better: LowerIsBetter, Last: 10, Cur: 10})
+	err = stats.RenderTo(filepath.Join(c.MkDir(), "test_scale.html"))
 	c.Assert(err, IsNil)
 	report, err := stats.Report()
 	c.Assert(err, IsNil)
-	c.Assert(strings.Contains(report, "p0: BalanceInterval"), Equals, true)
-	c.Assert(strings.Contains(report, "PR(last, red) is"), Equals, true)
+	c.Assert(strings.Contains(report, "BalanceInterval (s): last is 10.000000, cur is 10.000000"), Equals, true)
+	c.Assert(strings.Contains(report, "PrevBalanceLeaderCount: last is"), Equals, true)
+}
+
+func (s *testStatsSuite) TestMissingMetrics(c *C) {
+	last := &ScaleOutOnce{BalanceInterval: 100, CurLatency: 10, PrevBalanceLeaderCount: 5, CurBalanceLeaderCount: 10, BalanceLeaderOperators: 5}
+	cur := &ScaleOutOnce{BalanceInterval: 100, PrevBalanceLeaderCount: 5, BalanceLeaderOperators: -5,
+		Missing: []string{"CurLatency", "CurBalanceLeaderCount", "BalanceLeaderOperators"}}
+	stats := NewStats(cur)
+	c.Assert(stats.InitFrom(last, cur), IsNil)
+	cmp := stats.Compare("scale-out", DefaultThreshold)
+	verdicts := make(map[string]Verdict)
+	for _, m := range cmp.Metrics {
+		verdicts[m.Name] = m.Verdict
+	}
+	c.Assert(verdicts["BalanceInterval"], Equals, VerdictUnchanged)
+	c.Assert(verdicts["PrevBalanceLeaderCount"], Equals, VerdictUnchanged)
+	for _, name := range cur.Missing {
+		_, ok := verdicts[name]
+		c.Assert(ok, IsFalse, Commentf("missing metric %s is compared", name))
+	}
+
+	baseline, err := BaselineComparison("scale-out", cur)
+	c.Assert(err, IsNil)
+	for _, m := range baseline.Metrics {
+		for _, name := range cur.Missing {
+			c.Assert(m.Name, Not(Equals), name)
+		}
+	}
+}
+
+func (s *testStatsSuite) TestSampledStats(c *C) {
+	all := []*ScaleOutOnce{{BalanceInterval: 149, CurLatency: 8}, {BalanceInterval: 150, CurLatency: 12}, {BalanceInterval: 151, CurLatency: 16}}
+	samples, err := CollectSamples(all)
+	c.Assert(err, IsNil)
+	c.Assert(samples["BalanceInterval"], DeepEquals, []float64{149, 150, 151})
+	cur := MeanScaleOutOnce(all)
+	cur.Samples = samples
+	last := &ScaleOutOnce{BalanceInterval: 100, CurLatency: 10, Iterations: Iterations{Samples: map[string][]float64{
+		"BalanceInterval": {99, 100, 101},
+		"CurLatency":      {9, 10, 11},
+	}}}
+
+	stats := NewStats(cur)
+	c.Assert(stats.InitFrom(last, cur), IsNil)
+	cmp := stats.Compare("scale-out", DefaultThreshold)
+	verdicts := make(map[string]Verdict)
+	for _, m := range cmp.Metrics {
+		verdicts[m.Name] = m.Verdict
+	}
+	c.Assert(verdicts["BalanceInterval"], Equals, VerdictRegressed)
+	// the change of latency is noise among iterations
+	c.Assert(verdicts["CurLatency"], Equals, VerdictUnchanged)
+	report, err := stats.Report()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(report, "BalanceInterval (s): last is 100.000000, cur is 150.000000 ±"), IsTrue)
+	c.Assert(strings.Contains(report, "(n=3) p="), IsTrue)
+}
+
+type nestedStats struct {
+	Duration uint64 `bench:"name=duration,category=balance,unit=s,better=lower"`
+	Ignored  int    `bench:"-"`
+	Latency  struct {
+		P99 float32 `bench:"name=p99"`
+		P95 float64 `bench:"name=p95"`
+	} `bench:"name=latency,category=latency,unit=s,better=lower"`
+	Stores  map[string]int8 `bench:"name=store,category=store"`
+	Samples []float64
+	Name    string
+	hidden  int
+}
+
+func (s *testStatsSuite) TestParseMetrics(c *C) {
+	var rep nestedStats
+	rep.Duration = 100
+	rep.Ignored = 1
+	rep.Latency.P99 = 0.5
+	rep.Latency.P95 = 0.25
+	rep.Stores = map[string]int8{"2": 20, "1": 10}
+	rep.hidden = 1
+	metrics, err := ParseMetrics(&rep)
+	c.Assert(err, IsNil)
+	c.Assert(metrics, DeepEquals, []Metric{
+		{Name: "duration", Category: "balance", Unit: "s", Better: LowerIsBetter, Value: 100},
+		{Name: "latency.p99", Category: "latency", Unit: "s", Better: LowerIsBetter, Value: 0.5},
+		{Name: "latency.p95", Category: "latency", Unit: "s", Better: LowerIsBetter, Value: 0.25},
+		{Name: "store.1", Category: "store", Value: 10},
+		{Name: "store.2", Category: "store", Value: 20},
+	})
+
+	metrics, err = ParseMetrics(map[string]float64{"b": 2, "a": 1})
+	c.Assert(err, IsNil)
+	c.Assert(metrics, DeepEquals, []Metric{{Name: "a", Value: 1}, {Name: "b", Value: 2}})
+
+	_, err = ParseMetrics(1)
+	c.Assert(err, NotNil)
+}
+
+func (s *testStatsSuite) TestGenericStats(c *C) {
+	var last, cur nestedStats
+	last.Duration, cur.Duration = 100, 200
+	last.Stores = map[string]int8{"1": 10}
+	cur.Stores = map[string]int8{"1": 5, "4": 5}
+	bytes1, _ := json.Marshal(last)
+	bytes2, _ := json.Marshal(cur)
+	stats := NewStats(nestedStats{})
+	c.Assert(stats.Init(string(bytes1), string(bytes2)), IsNil)
+	// store 4 is not in last report
+	c.Assert(stats.Pairs(), HasLen, 4)
+	cmp := stats.Compare("test", DefaultThreshold)
+	c.Assert(cmp.Metrics[0].Verdict, Equals, VerdictRegressed)
+	c.Assert(cmp.Metrics[3].Verdict, Equals, VerdictChanged)
+
+	baseline, err := BaselineComparison("test", &cur)
+	c.Assert(err, IsNil)
+	c.Assert(baseline.Metrics, HasLen, 5)
+	c.Assert(baseline.Metrics[0].Verdict, Equals, VerdictBaseline)
+}
+
+func (s *testStatsSuite) TestMean(c *C) {
+	all := []*PDLeaderSwitchOnce{
+		{ElectionTime: 1, FailedQueries: 1, Missing: []string{"RegionHeartbeatResume"}},
+		{ElectionTime: 2, FailedQueries: 2},
+	}
+	mean := Mean(all).(*PDLeaderSwitchOnce)
+	c.Assert(mean.ElectionTime, Equals, 1.5)
+	c.Assert(mean.FailedQueries, Equals, 2)
+	c.Assert(mean.Missing, IsNil)
+	c.Assert(MeanScaleOutOnce(nil), DeepEquals, &ScaleOutOnce{})
+}
+
+func (s *testStatsSuite) TestAggregate(c *C) {
+	all := []*TPCCOnce{{TpmC: 100, Errors: 1}, {TpmC: 110, Errors: 2}, {TpmC: 120, Errors: 3}}
+	agg, err := Aggregate(all)
+	c.Assert(err, IsNil)
+	rep := agg.(*TPCCOnce)
+	c.Assert(rep.TpmC, Equals, 110.0)
+	c.Assert(rep.Samples["TpmC"], DeepEquals, []float64{100, 110, 120})
+	c.Assert(rep.Summary["TpmC"].N, Equals, 3)
+	c.Assert(rep.Summary["TpmC"].Stddev, Equals, 10.0)
+	c.Assert(rep.Summary["TpmC"].CILow < 110 && rep.Summary["TpmC"].CIHigh > 110, IsTrue)
+
+	// the summary is kept in the report, and samples are used to test significance
+	data, err := json.Marshal(rep)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(data), `"Summary":{`), IsTrue)
+	last, err := Aggregate([]*TPCCOnce{{TpmC: 105}, {TpmC: 125}, {TpmC: 95}})
+	c.Assert(err, IsNil)
+	lastData, err := json.Marshal(last)
+	c.Assert(err, IsNil)
+	stats := NewStats(rep)
+	c.Assert(stats.Init(string(lastData), string(data)), IsNil)
+	for _, m := range stats.Compare("tpcc", DefaultThreshold).Metrics {
+		if m.Name == "TpmC" {
+			c.Assert(m.Verdict, Equals, VerdictUnchanged)
+		}
+	}
+
+	pd, err := Aggregate([]*PDLeaderSwitchOnce{
+		{ElectionTime: 1, Missing: []string{"RegionHeartbeatResume"}},
+		{ElectionTime: 2, Missing: []string{"RegionHeartbeatResume", "TsoUnavailable"}},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(pd.(*PDLeaderSwitchOnce).Missing, DeepEquals, Missing{"RegionHeartbeatResume", "TsoUnavailable"})
+
+	// a single iteration has no samples
+	once, err := Aggregate([]*TPCCOnce{{TpmC: 100}})
+	c.Assert(err, IsNil)
+	c.Assert(once.(*TPCCOnce).Iterations, DeepEquals, Iterations{})
+}
+
+func (s *testStatsSuite) TestScaleOutCurve(c *C) {
+	points, err := ScaleOutCurve([]ScaleOutStep{
+		{Stores: 4, Added: 1, BalanceTime: 60, BalanceTimePerStore: 60, RegionOperators: 100},
+		{Stores: 6, Added: 2, BalanceTime: 100, BalanceTimePerStore: 50, RegionOperators: 150},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(points, HasLen, 2)
+	c.Assert(points[1].Label, Equals, "6 stores")
+	c.Assert(points[1].Metrics, HasLen, 7)
+	c.Assert(points[1].Metrics[0].Name, Equals, "BalanceTime")
+	c.Assert(points[1].Metrics[0].Value, Equals, 100.0)
 }
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// DatasetManifest is the file name of the manifest in a dataset.
+const DatasetManifest = "dataset.json"
+
+// Dataset describes a prebuilt dataset.
+type Dataset struct {
+	ID string `json:"id"`
+	// Rows is the row count of each table, the key is such as test.test_go_ycsb.
+	Rows map[string]int64 `json:"rows"`
+}
+
+// Tables returns the sorted tables in the dataset.
+func (d *Dataset) Tables() []string {
+	tables := make([]string, 0, len(d.Rows))
+	for table := range d.Rows {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// DBs returns the sorted databases of the tables in the dataset.
+func (d *Dataset) DBs() []string {
+	set := make(map[string]struct{})
+	for table := range d.Rows {
+		set[strings.SplitN(table, ".", 2)[0]] = struct{}{}
+	}
+	dbs := make([]string, 0, len(set))
+	for db := range set {
+		dbs = append(dbs, db)
+	}
+	sort.Strings(dbs)
+	return dbs
+}
+
+// Verify compares the row count of each table, which is returned by count, with the manifest.
+func (d *Dataset) Verify(count func(table string) (int64, error)) error {
+	for _, table := range d.Tables() {
+		rows, err := count(table)
+		if err != nil {
+			return err
+		}
+		if expected := d.Rows[table]; rows != expected {
+			return errors.Errorf("dataset %s is restored with %d rows in %s, expected %d", d.ID, rows, table, expected)
+		}
+	}
+	return nil
+}
+
+// RestoreSQL returns the statement which restores all databases from the storage.
+func RestoreSQL(s Storage) string {
+	return fmt.Sprintf("RESTORE DATABASE * FROM '%s'", s.URL())
+}
+
+// Storage is where prebuilt datasets are kept.
+type Storage interface {
+	// URL is the url of the dataset which is used by RESTORE.
+	URL() string
+	// ReadFile reads a file in the dataset.
+	ReadFile(name string) ([]byte, error)
+}
+
+// NewStorage returns the storage of kind, path is a directory for local,
+// and a bucket with an optional prefix for s3, such as mybucket/ycsb.
+func NewStorage(kind, path, endpoint string) (Storage, error) {
+	if path == "" {
+		return nil, errors.New("storage path is empty")
+	}
+	switch kind {
+	case "local":
+		return &localStorage{dir: path}, nil
+	case "s3":
+		parts := strings.SplitN(strings.Trim(path, "/"), "/", 2)
+		s := &s3Storage{endpoint: strings.TrimRight(endpoint, "/"), bucket: parts[0]}
+		if len(parts) == 2 {
+			s.prefix = parts[1]
+		}
+		return s, nil
+	default:
+		return nil, errors.Errorf("unknown storage %s, support list: local, s3", kind)
+	}
+}
+
+// ReadDataset reads the manifest of the dataset in the storage.
+func ReadDataset(s Storage) (*Dataset, error) {
+	data, err := s.ReadFile(DatasetManifest)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dataset{}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, errors.Annotate(err, "invalid dataset manifest")
+	}
+	if d.ID == "" {
+		return nil, errors.New("dataset id is empty")
+	}
+	return d, nil
+}
+
+// localStorage is a directory, it should be shared by all TiKV for restore.
+type localStorage struct {
+	dir string
+}
+
+func (s *localStorage) URL() string {
+	return "local://" + s.dir
+}
+
+func (s *localStorage) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(s.dir, name))
+}
+
+// s3Storage is an S3-compatible bucket, files are read anonymously by path-style requests.
+type s3Storage struct {
+	endpoint string
+	bucket   string
+	prefix   string
+}
+
+func (s *s3Storage) URL() string {
+	u := "s3://" + s.bucket
+	if s.prefix != "" {
+		u += "/" + s.prefix
+	}
+	if s.endpoint != "" {
+		u += "?endpoint=" + url.QueryEscape(s.endpoint)
+	}
+	return u
+}
+
+func (s *s3Storage) ReadFile(name string) ([]byte, error) {
+	endpoint := s.endpoint
+	if endpoint == "" {
+		endpoint = "https://s3.amazonaws.com"
+	}
+	key := name
+	if s.prefix != "" {
+		key = s.prefix + "/" + name
+	}
+	resp, err := http.Get(endpoint + "/" + s.bucket + "/" + key)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to read %s from s3: [%d] %s", key, resp.StatusCode, data)
+	}
+	return data, nil
+}
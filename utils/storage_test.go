@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
+)
+
+var _ = Suite(&testStorageSuite{})
+
+type testStorageSuite struct{}
+
+const manifest = `{"id": "ycsb-1k", "rows": {"test.test_go_ycsb": 1000}}`
+
+func (s *testStorageSuite) TestLocalStorage(c *C) {
+	dir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, DatasetManifest), []byte(manifest), 0644), IsNil)
+	storage, err := NewStorage("local", dir, "")
+	c.Assert(err, IsNil)
+	c.Assert(storage.URL(), Equals, "local://"+dir)
+	dataset, err := ReadDataset(storage)
+	c.Assert(err, IsNil)
+	c.Assert(dataset, DeepEquals, &Dataset{ID: "ycsb-1k", Rows: map[string]int64{"test.test_go_ycsb": 1000}})
+
+	storage, err = NewStorage("local", c.MkDir(), "")
+	c.Assert(err, IsNil)
+	_, err = ReadDataset(storage)
+	c.Assert(err, NotNil)
+
+	_, err = NewStorage("hdfs", dir, "")
+	c.Assert(err, NotNil)
+	_, err = NewStorage("local", "", "")
+	c.Assert(err, NotNil)
+}
+
+func (s *testStorageSuite) TestRestoreDataset(c *C) {
+	// a local fixture of a dataset with tables in two databases
+	dir := c.MkDir()
+	data := `{"id": "mix-1", "rows": {"test.test_go_ycsb": 1000, "tpcc.warehouse": 10, "tpcc.stock": 1000000}}`
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, DatasetManifest), []byte(data), 0644), IsNil)
+	storage, err := NewStorage("local", dir, "")
+	c.Assert(err, IsNil)
+	c.Assert(RestoreSQL(storage), Equals, "RESTORE DATABASE * FROM 'local://"+dir+"'")
+	dataset, err := ReadDataset(storage)
+	c.Assert(err, IsNil)
+	c.Assert(dataset.DBs(), DeepEquals, []string{"test", "tpcc"})
+	c.Assert(dataset.Tables(), DeepEquals, []string{"test.test_go_ycsb", "tpcc.stock", "tpcc.warehouse"})
+
+	restored := map[string]int64{"test.test_go_ycsb": 1000, "tpcc.warehouse": 10, "tpcc.stock": 1000000}
+	count := func(table string) (int64, error) { return restored[table], nil }
+	c.Assert(dataset.Verify(count), IsNil)
+	restored["tpcc.stock"] = 999999
+	c.Assert(dataset.Verify(count), ErrorMatches, "dataset mix-1 is restored with 999999 rows in tpcc.stock, expected 1000000")
+	c.Assert(dataset.Verify(func(string) (int64, error) { return 0, errors.New("table not found") }), ErrorMatches, "table not found")
+}
+
+func (s *testStorageSuite) TestS3Storage(c *C) {
+	// a local stand-in of the s3 endpoint
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mybucket/ycsb/"+DatasetManifest {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(manifest))
+	}))
+	defer srv.Close()
+
+	storage, err := NewStorage("s3", "mybucket/ycsb", srv.URL)
+	c.Assert(err, IsNil)
+	c.Assert(storage.URL(), Equals, "s3://mybucket/ycsb?endpoint="+url.QueryEscape(srv.URL))
+	dataset, err := ReadDataset(storage)
+	c.Assert(err, IsNil)
+	c.Assert(dataset.ID, Equals, "ycsb-1k")
+
+	storage, err = NewStorage("s3", "mybucket", srv.URL)
+	c.Assert(err, IsNil)
+	_, err = ReadDataset(storage)
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// SweepParam is a config and the values to sweep.
+type SweepParam struct {
+	Key    string
+	Values []string
+}
+
+// ParseSweep parses a matrix such as "leader-schedule-limit=4,8;store-limit=200,2000".
+func ParseSweep(spec string) ([]SweepParam, error) {
+	var params []SweepParam
+	keys := make(map[string]struct{})
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, errors.Errorf("invalid sweep param %s, it should be key=value1,value2", item)
+		}
+		param := SweepParam{Key: strings.TrimSpace(kv[0])}
+		if _, ok := keys[param.Key]; ok {
+			return nil, errors.Errorf("duplicated sweep param %s", param.Key)
+		}
+		keys[param.Key] = struct{}{}
+		for _, v := range strings.Split(kv[1], ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				param.Values = append(param.Values, v)
+			}
+		}
+		if len(param.Values) == 0 {
+			return nil, errors.Errorf("sweep param %s has no value", param.Key)
+		}
+		params = append(params, param)
+	}
+	if len(params) == 0 {
+		return nil, errors.New("sweep matrix is empty")
+	}
+	return params, nil
+}
+
+// SweepCombination is a value for each param of a sweep, in the order of params.
+type SweepCombination []KeyValue
+
+// KeyValue is a config and its value.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// Label returns the combination in text, such as "leader-schedule-limit=4 store-limit=200".
+func (c SweepCombination) Label() string {
+	items := make([]string, 0, len(c))
+	for _, kv := range c {
+		items = append(items, kv.Key+"="+kv.Value)
+	}
+	return strings.Join(items, " ")
+}
+
+// Combinations returns the cartesian product of params, the last param changes fastest.
+func Combinations(params []SweepParam) []SweepCombination {
+	combinations := []SweepCombination{nil}
+	for _, param := range params {
+		next := make([]SweepCombination, 0, len(combinations)*len(param.Values))
+		for _, prefix := range combinations {
+			for _, v := range param.Values {
+				combination := make(SweepCombination, len(prefix), len(prefix)+1)
+				copy(combination, prefix)
+				next = append(next, append(combination, KeyValue{Key: param.Key, Value: v}))
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// sweepTable is the value of each metric in each combination, the first combination is the baseline of delta.
+func sweepTable(points []TrendPoint) (header []string, rows [][]string) {
+	series := newPointSeries(points)
+	header = []string{"combination"}
+	var names []string
+	for _, category := range series.categories {
+		for _, name := range series.names[category] {
+			names = append(names, name)
+			header = append(header, metricLabel(name, series.units[name]))
+		}
+	}
+	for i, point := range points {
+		row := []string{point.Label}
+		for _, name := range names {
+			cur, ok := series.values[name][i].(float64)
+			if !ok {
+				row = append(row, "-")
+				continue
+			}
+			cell := formatFloat(cur)
+			if base, ok := series.values[name][0].(float64); ok && i > 0 {
+				if delta, ok := Delta(base, cur); ok {
+					cell += fmt.Sprintf(" (%+.2f%%)", delta)
+				}
+			}
+			row = append(row, cell)
+		}
+		rows = append(rows, row)
+	}
+	return header, rows
+}
+
+// RenderSweepTable writes a markdown table of sweep results, the delta is relative to the first combination.
+func RenderSweepTable(w io.Writer, title string, points []TrendPoint) error {
+	header, rows := sweepTable(points)
+	text := fmt.Sprintf("### %s\n\n", title)
+	text += "| " + strings.Join(header, " | ") + " |\n"
+	text += "|" + strings.Repeat(" --- |", len(header)) + "\n"
+	for _, row := range rows {
+		text += "| " + strings.Join(row, " | ") + " |\n"
+	}
+	_, err := io.WriteString(w, text)
+	return err
+}
+
+// RenderSweepCSV writes sweep results in csv.
+func RenderSweepCSV(w io.Writer, points []TrendPoint) error {
+	header, rows := sweepTable(points)
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
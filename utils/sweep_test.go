@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testSweepSuite{})
+
+type testSweepSuite struct{}
+
+func (s *testSweepSuite) TestParseSweep(c *C) {
+	params, err := ParseSweep("leader-schedule-limit=4,8; store-limit=200 ;")
+	c.Assert(err, IsNil)
+	c.Assert(params, DeepEquals, []SweepParam{
+		{Key: "leader-schedule-limit", Values: []string{"4", "8"}},
+		{Key: "store-limit", Values: []string{"200"}},
+	})
+
+	for _, spec := range []string{"", "store-limit", "=1", "store-limit=", "a=1;a=2"} {
+		_, err = ParseSweep(spec)
+		c.Assert(err, NotNil, Commentf("spec %q", spec))
+	}
+}
+
+func (s *testSweepSuite) TestCombinations(c *C) {
+	params := []SweepParam{
+		{Key: "a", Values: []string{"1", "2"}},
+		{Key: "b", Values: []string{"x", "y", "z"}},
+	}
+	combinations := Combinations(params)
+	c.Assert(combinations, HasLen, 6)
+	c.Assert(combinations[0].Label(), Equals, "a=1 b=x")
+	c.Assert(combinations[1].Label(), Equals, "a=1 b=y")
+	c.Assert(combinations[5].Label(), Equals, "a=2 b=z")
+}
+
+func (s *testSweepSuite) TestRenderSweepTable(c *C) {
+	points := []TrendPoint{
+		{Label: "a=1", Metrics: []Metric{{Name: "BalanceInterval", Category: "balance", Unit: "s", Value: 100}}},
+		{Label: "a=2", Metrics: []Metric{
+			{Name: "BalanceInterval", Category: "balance", Unit: "s", Value: 80},
+			{Name: "CurLatency", Category: "latency", Unit: "s", Value: 0.01},
+		}},
+	}
+	var buf bytes.Buffer
+	c.Assert(RenderSweepTable(&buf, "scale-out sweep", points), IsNil)
+	table := buf.String()
+	c.Assert(strings.Contains(table, "| combination | BalanceInterval (s) | CurLatency (s) |"), IsTrue)
+	c.Assert(strings.Contains(table, "| a=1 | 100 | - |"), IsTrue)
+	c.Assert(strings.Contains(table, "| a=2 | 80 (-20.00%) | 0.01 |"), IsTrue)
+
+	buf.Reset()
+	c.Assert(RenderSweepCSV(&buf, points), IsNil)
+	c.Assert(strings.Contains(buf.String(), "a=2,80 (-20.00%),0.01"), IsTrue)
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// SysbenchDB is the database of sysbench tables.
+const SysbenchDB = "sbtest"
+
+// Mixes of sysbench transactions.
+const (
+	SysbenchPointSelect = "point-select"
+	SysbenchReadWrite   = "read-write"
+	SysbenchUpdateIndex = "update-index"
+)
+
+// CheckSysbenchMix returns an error if the mix is unknown, an empty mix means no transaction.
+func CheckSysbenchMix(mix string) error {
+	switch mix {
+	case "", SysbenchPointSelect, SysbenchReadWrite, SysbenchUpdateIndex:
+		return nil
+	}
+	return errors.Errorf("unknown SYSBENCH_MIX %s, support list: %s,%s,%s", mix, SysbenchPointSelect, SysbenchReadWrite, SysbenchUpdateIndex)
+}
+
+// SysbenchTableName returns the name of the sysbench table, tables start from 1.
+func SysbenchTableName(table int) string {
+	return fmt.Sprintf("sbtest%d", table)
+}
+
+// SysbenchCreateTable returns the statement which creates a sysbench table with a secondary index on k.
+func SysbenchCreateTable(table int) string {
+	return fmt.Sprintf("CREATE TABLE %s (id INT NOT NULL, k INT NOT NULL DEFAULT 0, "+
+		"c CHAR(120) NOT NULL DEFAULT '', pad CHAR(60) NOT NULL DEFAULT '', PRIMARY KEY (id), KEY k_%d (k))", SysbenchTableName(table), table)
+}
+
+// SysbenchInsert returns the statement which inserts the rows of id in [start, end] into a table of tableSize rows.
+func SysbenchInsert(table, start, end, tableSize int) string {
+	values := make([]string, 0, end-start+1)
+	for id := start; id <= end; id++ {
+		values = append(values, sysbenchRow(id, tableSize))
+	}
+	return fmt.Sprintf("INSERT INTO %s (id, k, c, pad) VALUES %s", SysbenchTableName(table), strings.Join(values, ","))
+}
+
+func sysbenchRow(id, tableSize int) string {
+	return fmt.Sprintf("(%d, %d, '%s', '%s')", id, rand.Intn(tableSize)+1, RandomString(120), RandomString(60))
+}
+
+// SysbenchTransaction returns the statements of a random transaction of the mix,
+// the read-write mix follows oltp_read_write of sysbench.
+func SysbenchTransaction(mix string, tables, tableSize int) ([]string, error) {
+	table := SysbenchTableName(rand.Intn(tables) + 1)
+	id := rand.Intn(tableSize) + 1
+	switch mix {
+	case SysbenchPointSelect:
+		return []string{fmt.Sprintf("SELECT c FROM %s WHERE id = %d", table, id)}, nil
+	case SysbenchUpdateIndex:
+		return []string{fmt.Sprintf("UPDATE %s SET k = k + 1 WHERE id = %d", table, id)}, nil
+	case SysbenchReadWrite:
+	default:
+		return nil, errors.Errorf("no transaction of SYSBENCH_MIX %q", mix)
+	}
+	queries := []string{"BEGIN"}
+	for i := 0; i < 10; i++ {
+		queries = append(queries, fmt.Sprintf("SELECT c FROM %s WHERE id = %d", table, rand.Intn(tableSize)+1))
+	}
+	queries = append(queries,
+		fmt.Sprintf("SELECT c FROM %s WHERE id BETWEEN %d AND %d", table, id, id+99),
+		fmt.Sprintf("UPDATE %s SET k = k + 1 WHERE id = %d", table, id),
+		fmt.Sprintf("UPDATE %s SET c = '%s' WHERE id = %d", table, RandomString(120), rand.Intn(tableSize)+1),
+		fmt.Sprintf("DELETE FROM %s WHERE id = %d", table, id),
+		fmt.Sprintf("INSERT INTO %s (id, k, c, pad) VALUES %s", table, sysbenchRow(id, tableSize)),
+		"COMMIT",
+	)
+	return queries, nil
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// RandomString returns a random string of n lowercase letters and digits.
+func RandomString(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[rand.Intn(len(letters))]
+	}
+	return string(b)
+}
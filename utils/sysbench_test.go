@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testSysbenchSuite{})
+
+type testSysbenchSuite struct{}
+
+func (s *testSysbenchSuite) TestCheckMix(c *C) {
+	for _, mix := range []string{"", SysbenchPointSelect, SysbenchReadWrite, SysbenchUpdateIndex} {
+		c.Assert(CheckSysbenchMix(mix), IsNil)
+	}
+	c.Assert(CheckSysbenchMix("write-only"), NotNil)
+}
+
+func (s *testSysbenchSuite) TestTable(c *C) {
+	c.Assert(SysbenchTableName(3), Equals, "sbtest3")
+	create := SysbenchCreateTable(3)
+	c.Assert(strings.HasPrefix(create, "CREATE TABLE sbtest3 ("), IsTrue)
+	c.Assert(strings.Contains(create, "PRIMARY KEY (id), KEY k_3 (k)"), IsTrue)
+
+	insert := SysbenchInsert(3, 11, 20, 100)
+	c.Assert(strings.HasPrefix(insert, "INSERT INTO sbtest3 (id, k, c, pad) VALUES (11, "), IsTrue)
+	c.Assert(strings.Count(insert, "),("), Equals, 9)
+	c.Assert(strings.Contains(insert, "(20, "), IsTrue)
+	c.Assert(strings.Contains(insert, "(21, "), IsFalse)
+	c.Assert(len(RandomString(60)), Equals, 60)
+}
+
+func (s *testSysbenchSuite) TestTransaction(c *C) {
+	queries, err := SysbenchTransaction(SysbenchPointSelect, 1, 10)
+	c.Assert(err, IsNil)
+	c.Assert(queries, HasLen, 1)
+	c.Assert(strings.HasPrefix(queries[0], "SELECT c FROM sbtest1 WHERE id = "), IsTrue)
+
+	queries, err = SysbenchTransaction(SysbenchUpdateIndex, 1, 10)
+	c.Assert(err, IsNil)
+	c.Assert(queries, HasLen, 1)
+	c.Assert(strings.HasPrefix(queries[0], "UPDATE sbtest1 SET k = k + 1 WHERE id = "), IsTrue)
+
+	// 10 point selects, a range select, 2 updates, a delete and an insert of the deleted row
+	queries, err = SysbenchTransaction(SysbenchReadWrite, 1, 10)
+	c.Assert(err, IsNil)
+	c.Assert(queries, HasLen, 17)
+	c.Assert(queries[0], Equals, "BEGIN")
+	c.Assert(queries[16], Equals, "COMMIT")
+	c.Assert(strings.HasPrefix(queries[14], "DELETE FROM sbtest1 WHERE id = "), IsTrue)
+	id := strings.TrimPrefix(queries[14], "DELETE FROM sbtest1 WHERE id = ")
+	c.Assert(strings.HasPrefix(queries[15], "INSERT INTO sbtest1 (id, k, c, pad) VALUES ("+id+", "), IsTrue)
+
+	_, err = SysbenchTransaction("", 1, 10)
+	c.Assert(err, NotNil)
+	_, err = SysbenchTransaction("write-only", 1, 10)
+	c.Assert(err, NotNil)
+}
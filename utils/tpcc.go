@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// TPCCOnce is the stats of a TPC-C run.
+type TPCCOnce struct {
+	TpmC        float64 `json:"TpmC" bench:"category=throughput,unit=tpm,better=higher"`
+	NewOrderP95 float64 `json:"NewOrderP95" bench:"category=latency,unit=ms,better=lower"`
+	NewOrderP99 float64 `json:"NewOrderP99" bench:"category=latency,unit=ms,better=lower"`
+	// Errors is the count of failed transactions of all types.
+	Errors int `json:"Errors" bench:"category=error,better=lower"`
+	// Iterations keeps the value of each metric in every iteration.
+	Iterations `bench:"-"`
+	// Meta describes the run which produces the stats.
+	Meta *Metadata `json:"Meta,omitempty" bench:"-"`
+}
+
+var (
+	tpccSummaryRegexp = regexp.MustCompile(`\[Summary\] (\w+) - .*Count: (\d+),.*95th\(ms\): ([\d.]+), 99th\(ms\): ([\d.]+)`)
+	tpccTpmCRegexp    = regexp.MustCompile(`tpmC: ([\d.]+)`)
+)
+
+// ParseTPCCOutput parses the summary in the output of go-tpc tpcc run.
+func ParseTPCCOutput(out string) (*TPCCOnce, error) {
+	rep := &TPCCOnce{}
+	m := tpccTpmCRegexp.FindStringSubmatch(out)
+	if m == nil {
+		return nil, errors.New("no tpmC in the output of tpcc")
+	}
+	rep.TpmC, _ = strconv.ParseFloat(m[1], 64)
+	newOrder := false
+	for _, m := range tpccSummaryRegexp.FindAllStringSubmatch(out, -1) {
+		count, _ := strconv.Atoi(m[2])
+		switch {
+		case strings.HasSuffix(m[1], "_ERR"):
+			rep.Errors += count
+		case m[1] == "NEW_ORDER":
+			newOrder = true
+			rep.NewOrderP95, _ = strconv.ParseFloat(m[3], 64)
+			rep.NewOrderP99, _ = strconv.ParseFloat(m[4], 64)
+		}
+	}
+	if !newOrder {
+		return nil, errors.New("no summary of NEW_ORDER in the output of tpcc")
+	}
+	return rep, nil
+}
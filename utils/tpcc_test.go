@@ -0,0 +1,29 @@
+package utils
+
+import (
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testTPCCSuite{})
+
+type testTPCCSuite struct{}
+
+const tpccOutput = `[Current] NEW_ORDER - Takes(s): 10.0, Count: 100, TPM: 600.0, Sum(ms): 2000.0, Avg(ms): 20.0, 50th(ms): 18.9, 90th(ms): 30.4, 95th(ms): 35.7, 99th(ms): 50.3, 99.9th(ms): 60.8, Max(ms): 70.3
+Finished
+[Summary] DELIVERY - Takes(s): 599.9, Count: 1200, TPM: 120.0, Sum(ms): 60000.0, Avg(ms): 50.0, 50th(ms): 48.2, 90th(ms): 62.9, 95th(ms): 71.3, 99th(ms): 96.5, 99.9th(ms): 121.6, Max(ms): 176.2
+[Summary] NEW_ORDER - Takes(s): 600.0, Count: 13000, TPM: 1300.0, Sum(ms): 260000.0, Avg(ms): 20.0, 50th(ms): 18.9, 90th(ms): 29.4, 95th(ms): 33.6, 99th(ms): 48.2, 99.9th(ms): 83.9, Max(ms): 151.0
+[Summary] NEW_ORDER_ERR - Takes(s): 600.0, Count: 3, TPM: 0.3, Sum(ms): 30.0, Avg(ms): 10.0, 50th(ms): 9.4, 90th(ms): 12.1, 95th(ms): 12.1, 99th(ms): 12.1, 99.9th(ms): 12.1, Max(ms): 12.1
+[Summary] PAYMENT_ERR - Takes(s): 600.0, Count: 2, TPM: 0.2, Sum(ms): 20.0, Avg(ms): 10.0, 50th(ms): 9.4, 90th(ms): 12.1, 95th(ms): 12.1, 99th(ms): 12.1, 99.9th(ms): 12.1, Max(ms): 12.1
+tpmC: 1300.0, efficiency: 101.1%
+`
+
+func (s *testTPCCSuite) TestParseTPCCOutput(c *C) {
+	rep, err := ParseTPCCOutput(tpccOutput)
+	c.Assert(err, IsNil)
+	c.Assert(rep, DeepEquals, &TPCCOnce{TpmC: 1300, NewOrderP95: 33.6, NewOrderP99: 48.2, Errors: 5})
+
+	_, err = ParseTPCCOutput("Finished\n")
+	c.Assert(err, NotNil)
+	_, err = ParseTPCCOutput("tpmC: 1300.0\n")
+	c.Assert(err, NotNil)
+}
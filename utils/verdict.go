@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+)
+
+// Direction tells whether a lower or higher value of a metric is better.
+type Direction string
+
+const (
+	// LowerIsBetter is used by latency and duration.
+	LowerIsBetter Direction = "lower"
+	// HigherIsBetter is used by throughput.
+	HigherIsBetter Direction = "higher"
+	// NoDirection is used by metrics which are only informative.
+	NoDirection Direction = ""
+)
+
+// Verdict is the judgement of a compared metric.
+type Verdict string
+
+const (
+	// VerdictBaseline means there is no last value to compare.
+	VerdictBaseline Verdict = "baseline"
+	// VerdictUnchanged means the change is within threshold or not significant.
+	VerdictUnchanged Verdict = "unchanged"
+	// VerdictImproved means the metric becomes better.
+	VerdictImproved Verdict = "improved"
+	// VerdictRegressed means the metric becomes worse.
+	VerdictRegressed Verdict = "regressed"
+	// VerdictChanged means the metric changes but it has no direction.
+	VerdictChanged Verdict = "changed"
+)
+
+// DefaultThreshold is the percentage of delta under which a metric is unchanged.
+const DefaultThreshold = 10.0
+
+// Delta returns the relative change in percent from last to cur.
+// ok is false if last is 0 but cur is not, the relative change is undefined then.
+func Delta(last, cur float64) (delta float64, ok bool) {
+	if last == 0 {
+		return 0, cur == 0
+	}
+	return (cur - last) * 100 / math.Abs(last), true
+}
+
+// NewMetricDiff compares a metric, last is nil if there is no last report.
+// significant is false if samples of iterations show that the change is noise.
+func NewMetricDiff(category, name string, last *float64, cur float64, better Direction, threshold float64, significant bool) MetricDiff {
+	d := MetricDiff{
+		Category:  category,
+		Name:      name,
+		Last:      last,
+		Cur:       cur,
+		Better:    better,
+		Threshold: threshold,
+	}
+	if last == nil {
+		d.Verdict = VerdictBaseline
+		return d
+	}
+	delta, ok := Delta(*last, cur)
+	d.Delta, d.DeltaUndefined = delta, !ok
+	// a change from 0 always exceeds threshold
+	switch {
+	case (ok && math.Abs(d.Delta) <= threshold) || !significant:
+		d.Verdict = VerdictUnchanged
+	case better == NoDirection:
+		d.Verdict = VerdictChanged
+	case (cur < *last) == (better == LowerIsBetter):
+		d.Verdict = VerdictImproved
+	default:
+		d.Verdict = VerdictRegressed
+	}
+	return d
+}
+
+// FormatDelta returns the relative change in text, such as "+12.50%", or "from 0" if it is undefined.
+func (d *MetricDiff) FormatDelta() string {
+	if d.Last == nil {
+		return ""
+	}
+	if d.DeltaUndefined {
+		return "from 0"
+	}
+	return fmt.Sprintf("%+.2f%%", d.Delta)
+}
+
+// Regressions returns metrics which are regressed.
+func (c *Comparison) Regressions() []MetricDiff {
+	var ret []MetricDiff
+	for _, m := range c.Metrics {
+		if m.Verdict == VerdictRegressed {
+			ret = append(ret, m)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	. "github.com/pingcap/check"
+)
+
+type testVerdictSuite struct{}
+
+var _ = Suite(&testVerdictSuite{})
+
+func (s *testVerdictSuite) TestVerdict(c *C) {
+	cmp := newTestComparison()
+	verdicts := make([]Verdict, 0, len(cmp.Metrics))
+	for _, m := range cmp.Metrics {
+		verdicts = append(verdicts, m.Verdict)
+	}
+	c.Assert(verdicts, DeepEquals, []Verdict{VerdictRegressed, VerdictUnchanged, VerdictImproved, VerdictBaseline})
+	c.Assert(cmp.Regressions(), HasLen, 1)
+
+	last := 100.0
+	c.Assert(NewMetricDiff("balance", "balance_time", &last, 150, LowerIsBetter, DefaultThreshold, false).Verdict, Equals, VerdictUnchanged)
+	c.Assert(NewMetricDiff("balance", "balance_time", &last, 150, NoDirection, DefaultThreshold, true).Verdict, Equals, VerdictChanged)
+}
+
+func (s *testVerdictSuite) TestDelta(c *C) {
+	delta, ok := Delta(0.002, 0.004)
+	c.Assert(ok, IsTrue)
+	c.Assert(almostEqual(delta, 100, 1e-9), IsTrue)
+	delta, ok = Delta(1e9, 1.1e9)
+	c.Assert(ok, IsTrue)
+	c.Assert(almostEqual(delta, 10, 1e-9), IsTrue)
+	delta, ok = Delta(-4, -2)
+	c.Assert(ok, IsTrue)
+	c.Assert(delta, Equals, 50.0)
+	delta, ok = Delta(0, 0)
+	c.Assert(ok, IsTrue)
+	c.Assert(delta, Equals, 0.0)
+	_, ok = Delta(0, 5)
+	c.Assert(ok, IsFalse)
+
+	zero := 0.0
+	d := NewMetricDiff("schedule", "balance_region_operator_count", &zero, 5, LowerIsBetter, DefaultThreshold, true)
+	c.Assert(d.DeltaUndefined, IsTrue)
+	c.Assert(d.Verdict, Equals, VerdictRegressed)
+	c.Assert(d.FormatDelta(), Equals, "from 0")
+	last := 0.002
+	d = NewMetricDiff("latency", "cur_query_latency", &last, 0.001, LowerIsBetter, DefaultThreshold, true)
+	c.Assert(d.Verdict, Equals, VerdictImproved)
+	c.Assert(d.FormatDelta(), Equals, "-50.00%")
+}